@@ -1,43 +1,342 @@
 package config
 
 import (
+	"datamerge/internal/model"
+	"errors"
+	"fmt"
 	"github.com/spf13/viper"
+	"os"
+	"strings"
+	"time"
 )
 
+const (
+	AppEnvVariable = "APP_ENV"
+	DefaultAppEnv  = "local"
+	DefaultPath    = "."
+)
+
+// ImmutableConfig exposes the application configuration through read-only
+// getters so other packages do not depend on the concrete RootConfig
 type ImmutableConfig interface {
-	GetLogLevel()
-	GetSupplierConfig()
+	GetAppEnv() string
+	GetConfigFile() string
+	GetLogLevel() string
+	GetAdminAddr() string
+	GetSuppliers() []SupplierConfig
+	GetMappings() []*model.HotelMapping
+	GetLoadInterval() time.Duration
+	GetLoadJitter() time.Duration
+	GetSupplierTimeout() time.Duration
+	GetSupplierMaxRetries() int
+	GetSupplierRetryBackoff() time.Duration
+	GetSupplierRetryMaxBackoff() time.Duration
+	GetSupplierConcurrency() int
+	GetSupplierBreakerThreshold() int
+	GetSupplierBreakerCooldown() time.Duration
+	GetSupplierRateLimit() float64
+	GetSupplierRateBurst() int
+	GetSupplierMaxPayloadBytes() int64
+	GetSupplierMaxRecords() int
+	GetSupplierMaxRecordBytes() int64
+	GetSupplierURLPolicy() SupplierURLPolicy
+	GetDeadLetterCapacity() int
+	GetArchiveDir() string
+	GetArchiveRetention() time.Duration
+	GetArchiveMaxRuns() int
+	GetSnapshotDir() string
+	GetSnapshotInterval() time.Duration
+	GetSnapshotKeep() int
 }
 
 type RootConfig struct {
-	LogLevel       string `mapstructure:"LOG_LEVEL"`
-	SupplierConfig string `mapstructure:"SUPPLIER_CONFIG"`
+	AppEnv         string        `mapstructure:"-"`
+	LogLevel       string        `mapstructure:"LOG_LEVEL"`
+	AdminAddr      string        `mapstructure:"ADMIN_ADDR"`
+	SupplierConfig string        `mapstructure:"SUPPLIER_CONFIG"`
+	SuppliersFile  string        `mapstructure:"SUPPLIERS_FILE"`
+	MappingsDir    string        `mapstructure:"MAPPINGS_DIR"`
+	LoadInterval   time.Duration `mapstructure:"LOAD_INTERVAL"`
+	LoadJitter     time.Duration `mapstructure:"LOAD_JITTER"`
+
+	SupplierTimeout         time.Duration `mapstructure:"SUPPLIER_TIMEOUT"`
+	SupplierMaxRetries      int           `mapstructure:"SUPPLIER_MAX_RETRIES"`
+	SupplierRetryBackoff    time.Duration `mapstructure:"SUPPLIER_RETRY_BACKOFF"`
+	SupplierRetryMaxBackoff time.Duration `mapstructure:"SUPPLIER_RETRY_MAX_BACKOFF"`
+	SupplierConcurrency     int           `mapstructure:"SUPPLIER_CONCURRENCY"`
+
+	SupplierBreakerThreshold int           `mapstructure:"SUPPLIER_BREAKER_THRESHOLD"`
+	SupplierBreakerCooldown  time.Duration `mapstructure:"SUPPLIER_BREAKER_COOLDOWN"`
+	SupplierRateLimit        float64       `mapstructure:"SUPPLIER_RATE_LIMIT"`
+	SupplierRateBurst        int           `mapstructure:"SUPPLIER_RATE_BURST"`
+
+	SupplierMaxPayloadBytes int64 `mapstructure:"SUPPLIER_MAX_PAYLOAD_BYTES"`
+	SupplierMaxRecords      int   `mapstructure:"SUPPLIER_MAX_RECORDS"`
+	SupplierMaxRecordBytes  int64 `mapstructure:"SUPPLIER_MAX_RECORD_BYTES"`
+
+	SupplierAllowedSchemes       []string `mapstructure:"SUPPLIER_ALLOWED_SCHEMES"`
+	SupplierAllowedHosts         []string `mapstructure:"SUPPLIER_ALLOWED_HOSTS"`
+	SupplierBlockPrivateNetworks bool     `mapstructure:"SUPPLIER_BLOCK_PRIVATE_NETWORKS"`
+
+	DeadLetterCapacity int `mapstructure:"DEAD_LETTER_CAPACITY"`
+
+	ArchiveDir       string        `mapstructure:"ARCHIVE_DIR"`
+	ArchiveRetention time.Duration `mapstructure:"ARCHIVE_RETENTION"`
+	ArchiveMaxRuns   int           `mapstructure:"ARCHIVE_MAX_RUNS"`
+
+	SnapshotDir      string        `mapstructure:"SNAPSHOT_DIR"`
+	SnapshotInterval time.Duration `mapstructure:"SNAPSHOT_INTERVAL"`
+	SnapshotKeep     int           `mapstructure:"SNAPSHOT_KEEP"`
+
+	// Suppliers is resolved from either SUPPLIERS_FILE or SUPPLIER_CONFIG
+	Suppliers []SupplierConfig `mapstructure:"-"`
+	// Mappings are the supplier mappings read from MAPPINGS_DIR
+	Mappings []*model.HotelMapping `mapstructure:"-"`
+	// ConfigFile is the app.<env>.env file that was read, empty if none was found
+	ConfigFile string `mapstructure:"-"`
+}
+
+var _ ImmutableConfig = &RootConfig{}
+
+func (rc *RootConfig) GetAppEnv() string {
+	return rc.AppEnv
+}
+
+func (rc *RootConfig) GetConfigFile() string {
+	return rc.ConfigFile
 }
 
 func (rc *RootConfig) GetLogLevel() string {
 	return rc.LogLevel
 }
 
+func (rc *RootConfig) GetAdminAddr() string {
+	return rc.AdminAddr
+}
+
 func (rc *RootConfig) GetSupplierConfig() string {
 	return rc.SupplierConfig
 }
 
+func (rc *RootConfig) GetSuppliers() []SupplierConfig {
+	return rc.Suppliers
+}
+
+func (rc *RootConfig) GetMappings() []*model.HotelMapping {
+	return rc.Mappings
+}
+
+func (rc *RootConfig) GetLoadInterval() time.Duration {
+	return rc.LoadInterval
+}
+
+func (rc *RootConfig) GetLoadJitter() time.Duration {
+	return rc.LoadJitter
+}
+
+func (rc *RootConfig) GetSupplierTimeout() time.Duration {
+	return rc.SupplierTimeout
+}
+
+func (rc *RootConfig) GetSupplierMaxRetries() int {
+	return rc.SupplierMaxRetries
+}
+
+func (rc *RootConfig) GetSupplierRetryBackoff() time.Duration {
+	return rc.SupplierRetryBackoff
+}
+
+func (rc *RootConfig) GetSupplierRetryMaxBackoff() time.Duration {
+	return rc.SupplierRetryMaxBackoff
+}
+
+func (rc *RootConfig) GetSupplierConcurrency() int {
+	return rc.SupplierConcurrency
+}
+
+func (rc *RootConfig) GetSupplierBreakerThreshold() int {
+	return rc.SupplierBreakerThreshold
+}
+
+func (rc *RootConfig) GetSupplierBreakerCooldown() time.Duration {
+	return rc.SupplierBreakerCooldown
+}
+
+func (rc *RootConfig) GetSupplierRateLimit() float64 {
+	return rc.SupplierRateLimit
+}
+
+func (rc *RootConfig) GetSupplierRateBurst() int {
+	return rc.SupplierRateBurst
+}
+
+func (rc *RootConfig) GetSupplierMaxPayloadBytes() int64 {
+	return rc.SupplierMaxPayloadBytes
+}
+
+func (rc *RootConfig) GetSupplierMaxRecords() int {
+	return rc.SupplierMaxRecords
+}
+
+func (rc *RootConfig) GetSupplierMaxRecordBytes() int64 {
+	return rc.SupplierMaxRecordBytes
+}
+
+func (rc *RootConfig) GetSupplierURLPolicy() SupplierURLPolicy {
+	return SupplierURLPolicy{
+		AllowedSchemes:       trimList(rc.SupplierAllowedSchemes),
+		AllowedHosts:         trimList(rc.SupplierAllowedHosts),
+		BlockPrivateNetworks: rc.SupplierBlockPrivateNetworks,
+	}
+}
+
+func (rc *RootConfig) GetDeadLetterCapacity() int {
+	return rc.DeadLetterCapacity
+}
+
+func (rc *RootConfig) GetArchiveDir() string {
+	return rc.ArchiveDir
+}
+
+func (rc *RootConfig) GetArchiveRetention() time.Duration {
+	return rc.ArchiveRetention
+}
+
+func (rc *RootConfig) GetArchiveMaxRuns() int {
+	return rc.ArchiveMaxRuns
+}
+
+func (rc *RootConfig) GetSnapshotDir() string {
+	return rc.SnapshotDir
+}
+
+func (rc *RootConfig) GetSnapshotInterval() time.Duration {
+	return rc.SnapshotInterval
+}
+
+func (rc *RootConfig) GetSnapshotKeep() int {
+	return rc.SnapshotKeep
+}
+
+// trimList trims the entries of a comma separated setting and drops the empty ones
+func trimList(values []string) []string {
+	var trimmed []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
+// GetConfigFromEnv reads the app.<env>.env file of the environment selected
+// by the APP_ENV variable (local by default) from the working directory
 func GetConfigFromEnv() (*RootConfig, error) {
-	// use local config by default
-	viper.SetConfigType("env")
-	viper.AddConfigPath(".")
+	return LoadConfig(os.Getenv(AppEnvVariable), DefaultPath)
+}
+
+// LoadConfig builds the configuration of the given environment in layers:
+// built-in defaults, then the app.<env>.env file found in path (if any)
+// and finally the environment variables, which take precedence over both
+func LoadConfig(env string, path string) (*RootConfig, error) {
+	if env == "" {
+		env = DefaultAppEnv
+	}
+	v := viper.New()
+	v.SetConfigType("env")
+	v.AddConfigPath(path)
+	v.SetConfigName(fmt.Sprintf("app.%s", env))
+	setDefaults(v)
+	v.AutomaticEnv()
+
+	err := v.ReadInConfig()
+	if err != nil {
+		var notFoundErr viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFoundErr) {
+			return nil, err
+		}
+	}
 
-	viper.SetConfigName("app.local")
 	var config RootConfig
-	err := viper.ReadInConfig()
+	err = v.Unmarshal(&config)
 	if err != nil {
 		return nil, err
 	}
-
-	err = viper.Unmarshal(&config)
+	config.AppEnv = env
+	config.ConfigFile = v.ConfigFileUsed()
+	err = config.loadSuppliers()
+	if err != nil {
+		return nil, err
+	}
+	config.Mappings, err = ReadHotelMappings(config.MappingsDir)
 	if err != nil {
 		return nil, err
 	}
 	return &config, nil
+}
 
+// setDefaults registers a default for every setting, besides providing the
+// default values this is what allows AutomaticEnv to pick up environment
+// variables for settings missing from the config file
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("LOG_LEVEL", "warn")
+	// the admin endpoints are only reachable from the host by default
+	v.SetDefault("ADMIN_ADDR", "127.0.0.1:8081")
+	v.SetDefault("SUPPLIER_CONFIG", "")
+	v.SetDefault("SUPPLIERS_FILE", "")
+	v.SetDefault("MAPPINGS_DIR", "mappings")
+	v.SetDefault("LOAD_INTERVAL", "0s")
+	v.SetDefault("LOAD_JITTER", "0s")
+	v.SetDefault("SUPPLIER_TIMEOUT", "30s")
+	v.SetDefault("SUPPLIER_MAX_RETRIES", 3)
+	v.SetDefault("SUPPLIER_RETRY_BACKOFF", "500ms")
+	v.SetDefault("SUPPLIER_RETRY_MAX_BACKOFF", "10s")
+	v.SetDefault("SUPPLIER_CONCURRENCY", 4)
+	v.SetDefault("SUPPLIER_BREAKER_THRESHOLD", 5)
+	v.SetDefault("SUPPLIER_BREAKER_COOLDOWN", "1m")
+	v.SetDefault("SUPPLIER_RATE_LIMIT", 0)
+	v.SetDefault("SUPPLIER_RATE_BURST", 1)
+	v.SetDefault("SUPPLIER_MAX_PAYLOAD_BYTES", 256<<20)
+	v.SetDefault("SUPPLIER_MAX_RECORDS", 1000000)
+	v.SetDefault("SUPPLIER_MAX_RECORD_BYTES", 1<<20)
+	// file:// suppliers are opt-in, they can read any file the service can
+	v.SetDefault("SUPPLIER_ALLOWED_SCHEMES", "http,https")
+	v.SetDefault("SUPPLIER_ALLOWED_HOSTS", "")
+	v.SetDefault("SUPPLIER_BLOCK_PRIVATE_NETWORKS", true)
+	v.SetDefault("DEAD_LETTER_CAPACITY", 1000)
+	v.SetDefault("ARCHIVE_DIR", "")
+	v.SetDefault("ARCHIVE_RETENTION", "168h")
+	v.SetDefault("ARCHIVE_MAX_RUNS", 0)
+	v.SetDefault("SNAPSHOT_DIR", "")
+	v.SetDefault("SNAPSHOT_INTERVAL", "5m")
+	v.SetDefault("SNAPSHOT_KEEP", 3)
+}
+
+// loadSuppliers resolves the supplier list from the SUPPLIERS_FILE, falling
+// back to the legacy SUPPLIER_CONFIG string, and validates it
+func (rc *RootConfig) loadSuppliers() error {
+	var suppliers []SupplierConfig
+	var err error
+	switch {
+	case rc.SuppliersFile != "" && rc.SupplierConfig != "":
+		return &SupplierConfigError{Problems: []string{"SUPPLIERS_FILE and SUPPLIER_CONFIG are both set, use only one of them"}}
+	case rc.SuppliersFile != "":
+		suppliers, err = ReadSupplierConfigFile(rc.SuppliersFile)
+	case rc.SupplierConfig == "":
+		return &SupplierConfigError{Problems: []string{"no supplier configured, set SUPPLIERS_FILE or SUPPLIER_CONFIG"}}
+	default:
+		suppliers, err = ParseLegacySupplierConfig(rc.SupplierConfig)
+	}
+	if err != nil {
+		return err
+	}
+	err = ValidateSupplierConfigs(suppliers)
+	if err != nil {
+		return err
+	}
+	if problems := rc.GetSupplierURLPolicy().validateSupplierURLs(suppliers); len(problems) > 0 {
+		return &SupplierConfigError{Problems: problems}
+	}
+	rc.Suppliers = suppliers
+	return nil
 }
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeEnvFile(t *testing.T, dir, env, content string) {
+	path := filepath.Join(dir, "app."+env+".env")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoadConfig_SelectsFileOfEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=debug\nSUPPLIER_CONFIG=supplierA:http://localhost/local\n")
+	writeEnvFile(t, dir, "staging", "LOG_LEVEL=error\nSUPPLIER_CONFIG=supplierA:http://localhost/staging\n")
+	config, err := LoadConfig("staging", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, config.GetAppEnv(), "staging")
+	assert.Equal(t, config.GetConfigFile(), filepath.Join(dir, "app.staging.env"))
+	assert.Equal(t, config.GetLogLevel(), "error")
+	assert.Equal(t, config.GetSuppliers()[0].URL, "http://localhost/staging")
+}
+
+func TestLoadConfig_DefaultsToLocalEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:http://localhost/local\n")
+	config, err := LoadConfig("", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, config.GetAppEnv(), DefaultAppEnv)
+	assert.Equal(t, config.GetSuppliers()[0].URL, "http://localhost/local")
+}
+
+func TestLoadConfig_AppliesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:http://localhost/local\n")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, config.GetLogLevel(), "warn")
+	assert.Equal(t, config.GetAdminAddr(), "127.0.0.1:8081")
+	assert.Equal(t, config.GetSupplierTimeout(), 30*time.Second)
+	assert.Equal(t, config.GetSupplierMaxRetries(), 3)
+	assert.Equal(t, config.GetSupplierConcurrency(), 4)
+	assert.Equal(t, config.GetSupplierBreakerThreshold(), 5)
+	assert.Equal(t, config.GetSupplierBreakerCooldown(), time.Minute)
+	assert.Equal(t, config.GetSupplierRateLimit(), 0.0)
+	assert.Equal(t, config.GetDeadLetterCapacity(), 1000)
+	assert.Equal(t, config.GetArchiveDir(), "")
+	assert.Equal(t, config.GetArchiveRetention(), 7*24*time.Hour)
+	assert.Equal(t, config.GetSnapshotDir(), "")
+	assert.Equal(t, config.GetSnapshotInterval(), 5*time.Minute)
+	assert.Equal(t, config.GetSnapshotKeep(), 3)
+}
+
+func TestLoadConfig_ReadsRateLimit(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:http://localhost/local\nSUPPLIER_RATE_LIMIT=2.5\nSUPPLIER_RATE_BURST=3\n")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, config.GetSupplierRateLimit(), 2.5)
+	assert.Equal(t, config.GetSupplierRateBurst(), 3)
+}
+
+func TestLoadConfig_EnvironmentVariablesOverrideFile(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=debug\nSUPPLIER_CONFIG=supplierA:http://localhost/local\n")
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("SUPPLIER_TIMEOUT", "2s")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, config.GetLogLevel(), "error")
+	assert.Equal(t, config.GetSupplierTimeout(), 2*time.Second)
+}
+
+func TestLoadConfig_WithoutFileUsesEnvironmentVariables(t *testing.T) {
+	t.Setenv("SUPPLIER_CONFIG", "supplierA:http://localhost/env")
+	config, err := LoadConfig("production", t.TempDir())
+	assert.Nil(t, err)
+	assert.Empty(t, config.GetConfigFile())
+	assert.Equal(t, config.GetSuppliers()[0].URL, "http://localhost/env")
+}
+
+func TestLoadConfig_WithInvalidSuppliersReturnsError(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA\n")
+	_, err := LoadConfig("local", dir)
+	assert.Error(t, err)
+	assert.IsType(t, err, &SupplierConfigError{})
+}
+
+func TestLoadConfig_ReadsMappings(t *testing.T) {
+	dir := t.TempDir()
+	mappingsDir := filepath.Join(dir, "mappings")
+	assert.Nil(t, os.Mkdir(mappingsDir, 0o700))
+	assert.Nil(t, os.WriteFile(filepath.Join(mappingsDir, "supplierD.json"), []byte(`{"name": "supplierD", "fields": {"id": {"path": "code"}}}`), 0o600))
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierD:http://localhost/d\nMAPPINGS_DIR="+mappingsDir+"\n")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, len(config.GetMappings()), 1)
+	assert.Equal(t, config.GetMappings()[0].Name, "supplierD")
+
+	assert.Nil(t, os.WriteFile(filepath.Join(mappingsDir, "invalid.json"), []byte(`{"name": "supplierE", "fields": {}}`), 0o600))
+	_, err = LoadConfig("local", dir)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_ReadsSupplierURLPolicy(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:https://api.supplier.com/hotels\nSUPPLIER_ALLOWED_SCHEMES=https\nSUPPLIER_ALLOWED_HOSTS=api.supplier.com, *.partner.net\n")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, config.GetSupplierURLPolicy(), SupplierURLPolicy{
+		AllowedSchemes:       []string{"https"},
+		AllowedHosts:         []string{"api.supplier.com", "*.partner.net"},
+		BlockPrivateNetworks: true,
+	})
+}
+
+func TestLoadConfig_RejectsFileSupplierByDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:file:///etc/passwd\n")
+	_, err := LoadConfig("local", dir)
+	assert.IsType(t, err, &SupplierConfigError{})
+	assert.Contains(t, err.Error(), `scheme "file" is not allowed`)
+
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:file:///data/supplierA.json\nSUPPLIER_ALLOWED_SCHEMES=http,https,file\n")
+	_, err = LoadConfig("local", dir)
+	assert.Nil(t, err)
+}
+
+func TestLoadConfig_RejectsSupplierOutsideURLPolicy(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:http://localhost/a\nSUPPLIER_ALLOWED_SCHEMES=https\n")
+	_, err := LoadConfig("local", dir)
+	assert.IsType(t, err, &SupplierConfigError{})
+	assert.Contains(t, err.Error(), `supplier "supplierA" url is not allowed: scheme "http" is not allowed`)
+}
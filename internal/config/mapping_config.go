@@ -0,0 +1,52 @@
+package config
+
+import (
+	"datamerge/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ReadHotelMappings reads and validates every *.json supplier mapping of dir,
+// a missing directory simply means no mapping is configured
+func ReadHotelMappings(dir string) ([]*model.HotelMapping, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	var mappings []*model.HotelMapping
+	adapters := make(map[string]string)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var mapping model.HotelMapping
+		err = json.Unmarshal(content, &mapping)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse mapping %s: %w", file, err)
+		}
+		err = mapping.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		adapter := mapping.Name + model.AdapterVersionSeparator + mapping.GetVersion()
+		if previousFile, present := adapters[adapter]; present {
+			return nil, fmt.Errorf("mapping %s is declared in both %s and %s", adapter, previousFile, file)
+		}
+		adapters[adapter] = file
+		mappings = append(mappings, &mapping)
+	}
+	return mappings, nil
+}
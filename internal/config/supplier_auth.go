@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Authentication schemes a supplier can be configured with
+const (
+	SupplierAuthBearer = "bearer"
+	SupplierAuthBasic  = "basic"
+	SupplierAuthHmac   = "hmac"
+	SupplierAuthQuery  = "query"
+)
+
+// Default header names of the HMAC signature of a supplier request
+const (
+	DefaultHmacSignatureHeader = "X-Signature"
+	DefaultHmacTimestampHeader = "X-Timestamp"
+	DefaultHmacKeyIdHeader     = "X-Key-Id"
+)
+
+// SecretRef points to a secret kept out of the configuration files, either
+// in an environment variable or in a file (e.g. a mounted secret)
+type SecretRef struct {
+	Env  string `mapstructure:"env"`
+	File string `mapstructure:"file"`
+}
+
+// IsSet reports whether the reference points to a secret
+func (s SecretRef) IsSet() bool {
+	return s.Env != "" || s.File != ""
+}
+
+// Resolve reads the secret, a trailing newline of a secret file is dropped
+// The errors only name where the secret was looked up, never its value
+func (s SecretRef) Resolve() (string, error) {
+	switch {
+	case s.Env != "":
+		value, present := os.LookupEnv(s.Env)
+		if !present || value == "" {
+			return "", fmt.Errorf("secret environment variable %s is not set", s.Env)
+		}
+		return value, nil
+	case s.File != "":
+		content, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	return "", fmt.Errorf("secret has no env or file")
+}
+
+// SupplierAuthConfig describes how requests to a supplier are authenticated
+// Type selects the scheme: bearer sends Token as a bearer token, basic sends
+// Username and Password, query adds Token to the url as QueryParam and hmac
+// signs every request with Secret. Headers are sent with every request
+// whatever the scheme, for api keys and the like
+type SupplierAuthConfig struct {
+	Type            string               `mapstructure:"type"`
+	Token           SecretRef            `mapstructure:"token"`
+	Username        string               `mapstructure:"username"`
+	Password        SecretRef            `mapstructure:"password"`
+	QueryParam      string               `mapstructure:"query_param"`
+	KeyId           string               `mapstructure:"key_id"`
+	Secret          SecretRef            `mapstructure:"secret"`
+	SignatureHeader string               `mapstructure:"signature_header"`
+	TimestampHeader string               `mapstructure:"timestamp_header"`
+	Headers         map[string]SecretRef `mapstructure:"headers"`
+}
+
+// GetType returns the lower cased authentication scheme
+func (a *SupplierAuthConfig) GetType() string {
+	return strings.ToLower(a.Type)
+}
+
+// GetSignatureHeader returns the header carrying the HMAC signature
+func (a *SupplierAuthConfig) GetSignatureHeader() string {
+	if a.SignatureHeader == "" {
+		return DefaultHmacSignatureHeader
+	}
+	return a.SignatureHeader
+}
+
+// GetTimestampHeader returns the header carrying the signed timestamp
+func (a *SupplierAuthConfig) GetTimestampHeader() string {
+	if a.TimestampHeader == "" {
+		return DefaultHmacTimestampHeader
+	}
+	return a.TimestampHeader
+}
+
+// validate returns the problems of the auth configuration, secrets are only
+// resolved when the supplier is fetched so they can be rotated without a reload
+func (a *SupplierAuthConfig) validate() []string {
+	var problems []string
+	requireSecret := func(name string, secret SecretRef) {
+		if !secret.IsSet() {
+			problems = append(problems, fmt.Sprintf("%s auth requires a %s secret", a.Type, name))
+		} else if secret.Env != "" && secret.File != "" {
+			problems = append(problems, fmt.Sprintf("%s secret sets both env and file", name))
+		}
+	}
+	switch a.GetType() {
+	case "":
+	case SupplierAuthBearer:
+		requireSecret("token", a.Token)
+	case SupplierAuthBasic:
+		if a.Username == "" {
+			problems = append(problems, "basic auth requires a username")
+		}
+		requireSecret("password", a.Password)
+	case SupplierAuthQuery:
+		if a.QueryParam == "" {
+			problems = append(problems, "query auth requires a query_param")
+		}
+		requireSecret("token", a.Token)
+	case SupplierAuthHmac:
+		requireSecret("secret", a.Secret)
+	default:
+		problems = append(problems, fmt.Sprintf("auth type %q is not supported, use one of %s", a.Type,
+			strings.Join([]string{SupplierAuthBearer, SupplierAuthBasic, SupplierAuthQuery, SupplierAuthHmac}, ", ")))
+	}
+	for header, secret := range a.Headers {
+		if !secret.IsSet() {
+			problems = append(problems, fmt.Sprintf("auth header %q has no env or file secret", header))
+		}
+	}
+	return problems
+}
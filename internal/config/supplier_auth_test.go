@@ -0,0 +1,76 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretRef_Resolve(t *testing.T) {
+	t.Setenv("SUPPLIER_A_TOKEN", "env-token")
+	path := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(path, []byte("file-token\n"), 0o600))
+
+	value, err := SecretRef{Env: "SUPPLIER_A_TOKEN"}.Resolve()
+	assert.Nil(t, err)
+	assert.Equal(t, value, "env-token")
+	value, err = SecretRef{File: path}.Resolve()
+	assert.Nil(t, err)
+	assert.Equal(t, value, "file-token")
+
+	_, err = SecretRef{Env: "SUPPLIER_A_MISSING_TOKEN"}.Resolve()
+	assert.Error(t, err)
+	_, err = SecretRef{File: filepath.Join(t.TempDir(), "missing")}.Resolve()
+	assert.Error(t, err)
+	_, err = SecretRef{}.Resolve()
+	assert.Error(t, err)
+}
+
+func TestValidateSupplierConfigs_WithAuth(t *testing.T) {
+	err := ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "bearer", URL: "http://localhost/a", Auth: &SupplierAuthConfig{Type: "Bearer", Token: SecretRef{Env: "TOKEN"}}},
+		{Name: "basic", URL: "http://localhost/b", Auth: &SupplierAuthConfig{Type: "basic", Username: "user", Password: SecretRef{File: "/run/secrets/b"}}},
+		{Name: "hmac", URL: "http://localhost/c", Auth: &SupplierAuthConfig{Type: "hmac", Secret: SecretRef{Env: "SECRET"}}},
+		{Name: "query", URL: "http://localhost/d", Auth: &SupplierAuthConfig{Type: "query", QueryParam: "api_key", Token: SecretRef{Env: "KEY"}}},
+		{Name: "headers", URL: "http://localhost/e", Auth: &SupplierAuthConfig{Headers: map[string]SecretRef{"x-api-key": {Env: "KEY"}}}},
+	})
+	assert.Nil(t, err)
+
+	err = ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "bearer", URL: "http://localhost/a", Auth: &SupplierAuthConfig{Type: "bearer"}},
+		{Name: "basic", URL: "http://localhost/b", Auth: &SupplierAuthConfig{Type: "basic", Password: SecretRef{Env: "P", File: "/p"}}},
+		{Name: "query", URL: "http://localhost/d", Auth: &SupplierAuthConfig{Type: "query", Token: SecretRef{Env: "KEY"}}},
+		{Name: "oauth", URL: "http://localhost/e", Auth: &SupplierAuthConfig{Type: "oauth"}},
+		{Name: "file", URL: "file:///data/f.json", Auth: &SupplierAuthConfig{Headers: map[string]SecretRef{"x-api-key": {}}}},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, len(err.(*SupplierConfigError).Problems), 7)
+}
+
+func TestReadSupplierConfigFile_WithAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suppliers.yaml")
+	content := `
+suppliers:
+  - name: supplierA
+    url: https://localhost/a
+    auth:
+      type: hmac
+      key_id: datamerge
+      secret:
+        file: /run/secrets/supplierA
+      headers:
+        X-Api-Key:
+          env: SUPPLIER_A_API_KEY
+`
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	suppliers, err := ReadSupplierConfigFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateSupplierConfigs(suppliers))
+	auth := suppliers[0].Auth
+	assert.Equal(t, auth.GetType(), SupplierAuthHmac)
+	assert.Equal(t, auth.KeyId, "datamerge")
+	assert.Equal(t, auth.Secret, SecretRef{File: "/run/secrets/supplierA"})
+	assert.Equal(t, auth.Headers["x-api-key"], SecretRef{Env: "SUPPLIER_A_API_KEY"})
+	assert.Equal(t, auth.GetSignatureHeader(), DefaultHmacSignatureHeader)
+}
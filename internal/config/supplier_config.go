@@ -0,0 +1,335 @@
+package config
+
+import (
+	"datamerge/internal/model"
+	"fmt"
+	"github.com/spf13/viper"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const legacySupplierConfigSeparator = 2
+
+// Feed formats a supplier can publish its records in
+const (
+	FeedFormatJson   = "json"
+	FeedFormatNdjson = "ndjson"
+	FeedFormatCsv    = "csv"
+	FeedFormatXml    = "xml"
+)
+
+var supportedFeedFormats = []string{FeedFormatJson, FeedFormatNdjson, FeedFormatCsv, FeedFormatXml}
+
+// SupplierConfig describes a single supplier feed
+// Adapter is the name of the HotelLoaderData adapter used to bind the supplier
+// records, it defaults to the supplier Name. Suppliers are merged in ascending
+// Priority order (ties keep the order of declaration) so lower values take
+// precedence for the fields where the first value wins on merge
+// Format is the feed format of the supplier payloads, a JSON array by default
+// Auth holds the authentication of the supplier requests, its secrets are read
+// from the environment or from files so they never live in the configuration
+// TLS customizes the TLS connections to the supplier, e.g. for mutual TLS
+// RecordsPath is the dot separated path to the records array of a JSON feed
+// whose records are wrapped in an object, e.g. data for {"data": [...]}
+// Pagination, when set, makes the loader walk every page of the supplier api
+// RecordElement is the name of the elements holding the records of an XML
+// feed, every child element of the document root is a record when it is empty
+// RateLimit is the number of requests per second sent to the supplier with up
+// to RateBurst requests at once, it overrides SUPPLIER_RATE_LIMIT when set
+// MaxPayloadBytes, MaxRecords and MaxRecordBytes override the payload limits
+// of the SUPPLIER_MAX_* settings when set
+type SupplierConfig struct {
+	Name          string                    `mapstructure:"name" json:"name"`
+	Adapter       string                    `mapstructure:"adapter" json:"adapter"`
+	URL           string                    `mapstructure:"url" json:"url"`
+	Timeout       time.Duration             `mapstructure:"timeout" json:"timeout"`
+	Priority      int                       `mapstructure:"priority" json:"priority"`
+	Headers       map[string]string         `mapstructure:"headers" json:"-"`
+	Enabled       *bool                     `mapstructure:"enabled" json:"enabled"`
+	Format        string                    `mapstructure:"format" json:"format"`
+	RecordElement string                    `mapstructure:"record_element" json:"record_element"`
+	Auth          *SupplierAuthConfig       `mapstructure:"auth" json:"-"`
+	TLS           *SupplierTLSConfig        `mapstructure:"tls" json:"tls"`
+	RecordsPath   string                    `mapstructure:"records_path" json:"records_path"`
+	Pagination    *SupplierPaginationConfig `mapstructure:"pagination" json:"pagination"`
+	RateLimit     float64                   `mapstructure:"rate_limit" json:"rate_limit"`
+	RateBurst     int                       `mapstructure:"rate_burst" json:"rate_burst"`
+
+	MaxPayloadBytes int64 `mapstructure:"max_payload_bytes" json:"max_payload_bytes"`
+	MaxRecords      int   `mapstructure:"max_records" json:"max_records"`
+	MaxRecordBytes  int64 `mapstructure:"max_record_bytes" json:"max_record_bytes"`
+}
+
+// IsEnabled reports whether the supplier should be loaded, suppliers are
+// enabled unless explicitly disabled
+func (sc *SupplierConfig) IsEnabled() bool {
+	return sc.Enabled == nil || *sc.Enabled
+}
+
+// GetAdapter returns the adapter name, falling back to the supplier name
+func (sc *SupplierConfig) GetAdapter() string {
+	if sc.Adapter == "" {
+		return sc.Name
+	}
+	return sc.Adapter
+}
+
+// GetFormat returns the feed format, falling back to a JSON array
+func (sc *SupplierConfig) GetFormat() string {
+	if sc.Format == "" {
+		return FeedFormatJson
+	}
+	return strings.ToLower(sc.Format)
+}
+
+// SupplierConfigError lists every problem found while validating the
+// supplier configuration so they can all be fixed in one go
+type SupplierConfigError struct {
+	Problems []string
+}
+
+func (s *SupplierConfigError) Error() string {
+	return fmt.Sprintf("invalid supplier config: %s", strings.Join(s.Problems, "; "))
+}
+
+// ChangedSuppliers returns the names of the suppliers that were added,
+// removed or modified between the previous and current supplier lists
+func ChangedSuppliers(previous, current []SupplierConfig) []string {
+	previousByName := make(map[string]SupplierConfig, len(previous))
+	for _, supplier := range previous {
+		previousByName[supplier.Name] = supplier
+	}
+	var changed []string
+	for _, supplier := range current {
+		previousSupplier, present := previousByName[supplier.Name]
+		if !present || !reflect.DeepEqual(previousSupplier, supplier) {
+			changed = append(changed, supplier.Name)
+		}
+		delete(previousByName, supplier.Name)
+	}
+	for _, supplier := range previous {
+		if _, removed := previousByName[supplier.Name]; removed {
+			changed = append(changed, supplier.Name)
+		}
+	}
+	return changed
+}
+
+// ChangedConfigSuppliers returns the names of the suppliers to fetch again
+// once the current config replaces the previous one, that is the suppliers
+// whose config changed and the current suppliers bound with a mapping that
+// was added, removed or modified. A supplier adapter given without a version
+// is bound with the default version, which any mapping of that name can move
+func ChangedConfigSuppliers(previous, current ImmutableConfig) []string {
+	changed := ChangedSuppliers(previous.GetSuppliers(), current.GetSuppliers())
+	versions, names := changedMappings(previous.GetMappings(), current.GetMappings())
+	for _, supplier := range current.GetSuppliers() {
+		adapter := supplier.GetAdapter()
+		name, _, hasVersion := strings.Cut(adapter, model.AdapterVersionSeparator)
+		if (hasVersion && !versions[adapter]) || (!hasVersion && !names[name]) {
+			continue
+		}
+		if !containsString(changed, supplier.Name) {
+			changed = append(changed, supplier.Name)
+		}
+	}
+	return changed
+}
+
+// changedMappings returns the name@version and the name of every mapping
+// that was added, removed or modified between previous and current
+func changedMappings(previous, current []*model.HotelMapping) (versions, names map[string]bool) {
+	byVersion := func(mappings []*model.HotelMapping) map[string]*model.HotelMapping {
+		result := make(map[string]*model.HotelMapping, len(mappings))
+		for _, mapping := range mappings {
+			result[mapping.Name+model.AdapterVersionSeparator+mapping.GetVersion()] = mapping
+		}
+		return result
+	}
+	previousByVersion, currentByVersion := byVersion(previous), byVersion(current)
+	versions, names = make(map[string]bool), make(map[string]bool)
+	record := func(adapter string, mapping *model.HotelMapping) {
+		versions[adapter] = true
+		names[mapping.Name] = true
+	}
+	for adapter, mapping := range currentByVersion {
+		if !mapping.Equal(previousByVersion[adapter]) {
+			record(adapter, mapping)
+		}
+	}
+	for adapter, mapping := range previousByVersion {
+		if _, present := currentByVersion[adapter]; !present {
+			record(adapter, mapping)
+		}
+	}
+	return versions, names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLegacySupplierConfig parses the comma separated supplierName:url pairs
+// of the SUPPLIER_CONFIG variable
+func ParseLegacySupplierConfig(configs string) ([]SupplierConfig, error) {
+	var suppliers []SupplierConfig
+	var problems []string
+	for i, config := range strings.Split(configs, ",") {
+		config = strings.TrimSpace(config)
+		configSplit := strings.SplitN(config, ":", legacySupplierConfigSeparator)
+		if len(configSplit) != 2 || configSplit[0] == "" || configSplit[1] == "" {
+			problems = append(problems, fmt.Sprintf("SUPPLIER_CONFIG entry %d %q is not in the supplierName:url format", i+1, config))
+			continue
+		}
+		suppliers = append(suppliers, SupplierConfig{
+			Name: strings.TrimSpace(configSplit[0]),
+			URL:  strings.TrimSpace(configSplit[1]),
+		})
+	}
+	if len(problems) > 0 {
+		return nil, &SupplierConfigError{Problems: problems}
+	}
+	return suppliers, nil
+}
+
+// ReadSupplierConfigFile reads the suppliers section of a YAML or JSON file,
+// the format is picked from the file extension
+func ReadSupplierConfigFile(path string) ([]SupplierConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+	var suppliers []SupplierConfig
+	err = v.UnmarshalKey("suppliers", &suppliers)
+	if err != nil {
+		return nil, err
+	}
+	return suppliers, nil
+}
+
+// ValidateSupplierConfigs checks that every supplier has a unique name and a
+// valid url, a SupplierConfigError listing every problem is returned otherwise
+func ValidateSupplierConfigs(suppliers []SupplierConfig) error {
+	var problems []string
+	if len(suppliers) == 0 {
+		problems = append(problems, "no supplier configured")
+	}
+	names := make(map[string]bool)
+	for i, supplier := range suppliers {
+		if supplier.Name == "" {
+			problems = append(problems, fmt.Sprintf("supplier %d has no name", i+1))
+		} else if names[supplier.Name] {
+			problems = append(problems, fmt.Sprintf("supplier %q is declared more than once", supplier.Name))
+		}
+		names[supplier.Name] = true
+		if supplier.Timeout < 0 {
+			problems = append(problems, fmt.Sprintf("supplier %q has a negative timeout", supplier.Name))
+		}
+		if supplier.RateLimit < 0 || supplier.RateBurst < 0 {
+			problems = append(problems, fmt.Sprintf("supplier %q has a negative rate_limit or rate_burst", supplier.Name))
+		}
+		if supplier.MaxPayloadBytes < 0 || supplier.MaxRecords < 0 || supplier.MaxRecordBytes < 0 {
+			problems = append(problems, fmt.Sprintf("supplier %q has a negative payload limit", supplier.Name))
+		}
+		if problem := validateSupplierURL(supplier.URL); problem != "" {
+			problems = append(problems, fmt.Sprintf("supplier %q %s", supplier.Name, problem))
+		}
+		if !isSupportedFeedFormat(supplier.GetFormat()) {
+			problems = append(problems, fmt.Sprintf("supplier %q format %q is not supported, use one of %s",
+				supplier.Name, supplier.Format, strings.Join(supportedFeedFormats, ", ")))
+		}
+		if _, isFile := FileSourcePath(supplier.URL); isFile && supplier.Auth != nil {
+			problems = append(problems, fmt.Sprintf("supplier %q auth only applies to http and https urls", supplier.Name))
+		}
+		if _, isFile := FileSourcePath(supplier.URL); isFile && supplier.TLS != nil {
+			problems = append(problems, fmt.Sprintf("supplier %q tls only applies to https urls", supplier.Name))
+		}
+		if supplier.TLS != nil {
+			for _, problem := range supplier.TLS.validate() {
+				problems = append(problems, fmt.Sprintf("supplier %q %s", supplier.Name, problem))
+			}
+		}
+		if supplier.Auth != nil {
+			for _, problem := range supplier.Auth.validate() {
+				problems = append(problems, fmt.Sprintf("supplier %q %s", supplier.Name, problem))
+			}
+		}
+		if supplier.RecordsPath != "" && supplier.GetFormat() != FeedFormatJson {
+			problems = append(problems, fmt.Sprintf("supplier %q records_path only applies to the %s format", supplier.Name, FeedFormatJson))
+		}
+		if supplier.Pagination != nil {
+			if _, isFile := FileSourcePath(supplier.URL); isFile {
+				problems = append(problems, fmt.Sprintf("supplier %q pagination only applies to http and https urls", supplier.Name))
+			}
+			for _, problem := range supplier.Pagination.validate(supplier.GetFormat()) {
+				problems = append(problems, fmt.Sprintf("supplier %q %s", supplier.Name, problem))
+			}
+		}
+		if supplier.RecordElement != "" && supplier.GetFormat() != FeedFormatXml {
+			problems = append(problems, fmt.Sprintf("supplier %q record_element only applies to the %s format", supplier.Name, FeedFormatXml))
+		}
+	}
+	if len(problems) > 0 {
+		return &SupplierConfigError{Problems: problems}
+	}
+	return nil
+}
+
+func isSupportedFeedFormat(format string) bool {
+	for _, supported := range supportedFeedFormats {
+		if format == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// FileURLPrefix is the prefix of supplier urls that read local files, the rest
+// of the url is a file, a directory or a glob pattern
+const FileURLPrefix = "file://"
+
+// FileSourcePath returns the local path of a file:// supplier url, ok is false
+// for any other url. The path is taken verbatim so glob patterns are not
+// mistaken for url query or fragment delimiters
+func FileSourcePath(rawURL string) (path string, ok bool) {
+	if !strings.HasPrefix(strings.ToLower(rawURL), FileURLPrefix) {
+		return "", false
+	}
+	return rawURL[len(FileURLPrefix):], true
+}
+
+func validateSupplierURL(rawURL string) string {
+	if rawURL == "" {
+		return "has no url"
+	}
+	if path, ok := FileSourcePath(rawURL); ok {
+		if path == "" {
+			return "file url has no path"
+		}
+		if _, err := filepath.Match(path, ""); err != nil {
+			return fmt.Sprintf("file url has an invalid pattern: %s", err)
+		}
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Sprintf("has an invalid url: %s", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Sprintf("url scheme %q is not supported, use http, https or file", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "url has no host"
+	}
+	return ""
+}
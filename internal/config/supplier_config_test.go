@@ -0,0 +1,146 @@
+package config
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseLegacySupplierConfig_WithValidConfig(t *testing.T) {
+	suppliers, err := ParseLegacySupplierConfig("supplierA:http://localhost/a, supplierB:https://localhost:8443/b")
+	assert.Nil(t, err)
+	assert.Equal(t, len(suppliers), 2)
+	assert.Equal(t, suppliers[0].Name, "supplierA")
+	assert.Equal(t, suppliers[0].GetAdapter(), "supplierA")
+	assert.Equal(t, suppliers[1].URL, "https://localhost:8443/b")
+	assert.True(t, suppliers[1].IsEnabled())
+}
+
+func TestParseLegacySupplierConfig_WithMalformedEntryReturnsError(t *testing.T) {
+	_, err := ParseLegacySupplierConfig("supplierA:http://localhost/a,supplierB")
+	assert.Error(t, err)
+	assert.IsType(t, err, &SupplierConfigError{})
+	assert.Contains(t, err.Error(), `"supplierB"`)
+}
+
+func TestValidateSupplierConfigs_ReportsEveryProblem(t *testing.T) {
+	err := ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "supplierA", URL: "http://localhost/a"},
+		{Name: "supplierA", URL: "ftp://localhost/a"},
+		{URL: "http://localhost/c", Timeout: -time.Second},
+		{Name: "supplierD", URL: "http://localhost/d", Format: "yaml"},
+		{Name: "supplierE", URL: "http://localhost/e", RateLimit: -1},
+	})
+	assert.Error(t, err)
+	configErr := err.(*SupplierConfigError)
+	assert.Equal(t, len(configErr.Problems), 6)
+	assert.Contains(t, err.Error(), `format "yaml" is not supported`)
+	assert.Contains(t, err.Error(), `supplier "supplierE" has a negative rate_limit or rate_burst`)
+}
+
+func TestValidateSupplierConfigs_WithFileUrls(t *testing.T) {
+	err := ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "supplierA", URL: "file:///var/exports/supplierA.json"},
+		{Name: "supplierB", URL: "file://exports/supplierB"},
+		{Name: "supplierC", URL: "file://exports/supplierC-?.json"},
+	})
+	assert.Nil(t, err)
+	err = ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "supplierA", URL: "file://"},
+		{Name: "supplierB", URL: "file://exports/[supplierB"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, len(err.(*SupplierConfigError).Problems), 2)
+}
+
+func TestFileSourcePath(t *testing.T) {
+	path, ok := FileSourcePath("file://exports/supplierC-?.json")
+	assert.True(t, ok)
+	assert.Equal(t, path, "exports/supplierC-?.json")
+	_, ok = FileSourcePath("http://localhost/a")
+	assert.False(t, ok)
+}
+
+func TestReadSupplierConfigFile_WithYamlFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suppliers.yaml")
+	content := `
+suppliers:
+  - name: supplierA
+    url: http://localhost/a
+    timeout: 5s
+    priority: 2
+    headers:
+      X-Api-Key: key
+  - name: legacyB
+    adapter: supplierB
+    url: http://localhost/b
+    enabled: false
+    format: NDJSON
+`
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	suppliers, err := ReadSupplierConfigFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateSupplierConfigs(suppliers))
+	assert.Equal(t, len(suppliers), 2)
+	assert.Equal(t, suppliers[0].Timeout, 5*time.Second)
+	assert.Equal(t, suppliers[0].Priority, 2)
+	assert.Equal(t, suppliers[0].Headers["x-api-key"], "key")
+	assert.Equal(t, suppliers[1].GetAdapter(), "supplierB")
+	assert.False(t, suppliers[1].IsEnabled())
+	assert.Equal(t, suppliers[0].GetFormat(), FeedFormatJson)
+	assert.Equal(t, suppliers[1].GetFormat(), FeedFormatNdjson)
+}
+
+func TestValidateSupplierConfigs_WithPagination(t *testing.T) {
+	err := ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "page", URL: "http://localhost/a", Pagination: &SupplierPaginationConfig{Type: "page", SizeParam: "limit", Size: 100}},
+		{Name: "cursor", URL: "http://localhost/b", RecordsPath: "data", Pagination: &SupplierPaginationConfig{Type: "cursor", CursorPath: "next"}},
+		{Name: "link", URL: "http://localhost/c", Format: FeedFormatCsv, Pagination: &SupplierPaginationConfig{Type: "link"}},
+	})
+	assert.Nil(t, err)
+
+	err = ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "cursor", URL: "http://localhost/a", Format: FeedFormatNdjson, Pagination: &SupplierPaginationConfig{Type: "cursor"}},
+		{Name: "size", URL: "http://localhost/b", Pagination: &SupplierPaginationConfig{Type: "offset", Size: 100}},
+		{Name: "file", URL: "file:///data/c.json", Pagination: &SupplierPaginationConfig{Type: "scroll"}},
+		{Name: "records", URL: "http://localhost/d", Format: FeedFormatCsv, RecordsPath: "data"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, len(err.(*SupplierConfigError).Problems), 6)
+}
+
+func TestSupplierPaginationConfig_Defaults(t *testing.T) {
+	page := SupplierPaginationConfig{Type: "Page"}
+	assert.Equal(t, page.GetParam(), "page")
+	assert.Equal(t, page.GetStart(), 1)
+	assert.Equal(t, page.GetMaxPages(), DefaultMaxPages)
+	start := 0
+	offset := SupplierPaginationConfig{Type: "offset", Param: "skip", Start: &start, MaxPages: 10}
+	assert.Equal(t, offset.GetParam(), "skip")
+	assert.Equal(t, offset.GetStart(), 0)
+	assert.Equal(t, offset.GetMaxPages(), 10)
+}
+
+func TestValidateSupplierConfigs_WithTLS(t *testing.T) {
+	err := ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "ca", URL: "https://localhost/a", TLS: &SupplierTLSConfig{CAFile: "/etc/ca.pem", MinVersion: "1.3"}},
+		{Name: "mtls", URL: "https://localhost/b", TLS: &SupplierTLSConfig{CertFile: "/run/client.crt", KeyFile: "/run/client.key", ServerName: "b.local"}},
+	})
+	assert.Nil(t, err)
+
+	err = ValidateSupplierConfigs([]SupplierConfig{
+		{Name: "version", URL: "https://localhost/a", TLS: &SupplierTLSConfig{MinVersion: "1.4"}},
+		{Name: "key", URL: "https://localhost/b", TLS: &SupplierTLSConfig{CertFile: "/run/client.crt"}},
+		{Name: "file", URL: "file:///data/c.json", TLS: &SupplierTLSConfig{CAFile: "/etc/ca.pem"}},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, len(err.(*SupplierConfigError).Problems), 3)
+}
+
+func TestSupplierTLSConfig_GetMinVersion(t *testing.T) {
+	assert.Equal(t, (&SupplierTLSConfig{}).GetMinVersion(), uint16(tls.VersionTLS12))
+	assert.Equal(t, (&SupplierTLSConfig{MinVersion: "1.3"}).GetMinVersion(), uint16(tls.VersionTLS13))
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pagination strategies of a paginated supplier api
+const (
+	PaginationPage   = "page"
+	PaginationOffset = "offset"
+	PaginationCursor = "cursor"
+	PaginationLink   = "link"
+)
+
+// DefaultMaxPages caps the number of pages fetched from a paginated supplier
+const DefaultMaxPages = 100
+
+// SupplierPaginationConfig describes how the pages of a supplier api are walked
+// page increments the Param query parameter from Start (default 1), offset
+// increments it by the number of records of every page from Start (default 0),
+// both stop at the first empty page. cursor sends the value found at CursorPath
+// in the body of a page as the Param of the next one until there is none, and
+// link follows the rel="next" url of the Link response header. SizeParam and
+// Size optionally request a page size. Walking more than MaxPages pages fails
+// the supplier
+type SupplierPaginationConfig struct {
+	Type       string `mapstructure:"type" json:"type"`
+	Param      string `mapstructure:"param" json:"param"`
+	Start      *int   `mapstructure:"start" json:"start"`
+	SizeParam  string `mapstructure:"size_param" json:"size_param"`
+	Size       int    `mapstructure:"size" json:"size"`
+	CursorPath string `mapstructure:"cursor_path" json:"cursor_path"`
+	MaxPages   int    `mapstructure:"max_pages" json:"max_pages"`
+}
+
+// GetType returns the lower cased pagination strategy
+func (p *SupplierPaginationConfig) GetType() string {
+	return strings.ToLower(p.Type)
+}
+
+// GetParam returns the query parameter carrying the page, offset or cursor
+func (p *SupplierPaginationConfig) GetParam() string {
+	if p.Param != "" {
+		return p.Param
+	}
+	return p.GetType()
+}
+
+// GetStart returns the first page number or offset
+func (p *SupplierPaginationConfig) GetStart() int {
+	if p.Start != nil {
+		return *p.Start
+	}
+	if p.GetType() == PaginationPage {
+		return 1
+	}
+	return 0
+}
+
+// GetMaxPages returns the maximum number of pages fetched
+func (p *SupplierPaginationConfig) GetMaxPages() int {
+	if p.MaxPages <= 0 {
+		return DefaultMaxPages
+	}
+	return p.MaxPages
+}
+
+func (p *SupplierPaginationConfig) validate(format string) []string {
+	var problems []string
+	switch p.GetType() {
+	case PaginationPage, PaginationOffset, PaginationLink:
+	case PaginationCursor:
+		if p.CursorPath == "" {
+			problems = append(problems, "cursor pagination requires a cursor_path")
+		}
+		if format != FeedFormatJson {
+			problems = append(problems, fmt.Sprintf("cursor pagination requires the %s format", FeedFormatJson))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("pagination type %q is not supported, use one of %s", p.Type,
+			strings.Join([]string{PaginationPage, PaginationOffset, PaginationCursor, PaginationLink}, ", ")))
+	}
+	if p.Size < 0 || p.MaxPages < 0 {
+		problems = append(problems, "pagination size and max_pages cannot be negative")
+	}
+	if p.Size > 0 && p.SizeParam == "" {
+		problems = append(problems, "pagination size requires a size_param")
+	}
+	return problems
+}
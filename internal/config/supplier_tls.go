@@ -0,0 +1,55 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+)
+
+// DefaultTLSMinVersion is the minimum TLS version of the supplier connections
+const DefaultTLSMinVersion = "1.2"
+
+var supportedTLSVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// SupplierTLSConfig describes the TLS connections to a supplier
+// CAFile is a PEM bundle of the certificate authorities trusted for the
+// supplier, they replace the system ones. CertFile and KeyFile are the PEM
+// client certificate and key presented for mutual TLS. ServerName overrides
+// the name the supplier certificate is verified against, e.g. when the
+// supplier is reached through an IP address
+type SupplierTLSConfig struct {
+	CAFile     string `mapstructure:"ca_file" json:"ca_file"`
+	CertFile   string `mapstructure:"cert_file" json:"cert_file"`
+	KeyFile    string `mapstructure:"key_file" json:"key_file"`
+	MinVersion string `mapstructure:"min_version" json:"min_version"`
+	ServerName string `mapstructure:"server_name" json:"server_name"`
+}
+
+// GetMinVersion returns the minimum TLS version, TLS 1.2 by default
+func (t *SupplierTLSConfig) GetMinVersion() uint16 {
+	if t.MinVersion == "" {
+		return supportedTLSVersions[DefaultTLSMinVersion]
+	}
+	return supportedTLSVersions[t.MinVersion]
+}
+
+func (t *SupplierTLSConfig) validate() []string {
+	var problems []string
+	if _, supported := supportedTLSVersions[t.MinVersion]; t.MinVersion != "" && !supported {
+		var versions []string
+		for version := range supportedTLSVersions {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+		problems = append(problems, fmt.Sprintf("tls min_version %q is not supported, use one of %v", t.MinVersion, versions))
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		problems = append(problems, "tls cert_file and key_file must be set together")
+	}
+	return problems
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SupplierURLPolicy restricts the supplier urls the loader fetches, so a
+// supplier config cannot be used to reach internal services
+// AllowedSchemes and AllowedHosts are allowlists, any scheme or host is allowed
+// when they are empty. A host is either a hostname, an IP address or a
+// *.example.com pattern matching every subdomain of example.com
+// BlockPrivateNetworks rejects connections to loopback, private and link-local
+// addresses, it is checked against the resolved address of every connection
+type SupplierURLPolicy struct {
+	AllowedSchemes       []string
+	AllowedHosts         []string
+	BlockPrivateNetworks bool
+}
+
+// CheckURL checks the scheme and the host of a supplier url against the
+// allowlists, the reason the url is not allowed is returned otherwise
+func (p SupplierURLPolicy) CheckURL(rawURL string) error {
+	if _, isFile := FileSourcePath(rawURL); isFile {
+		return p.checkScheme("file")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	err = p.checkScheme(parsed.Scheme)
+	if err != nil {
+		return err
+	}
+	return p.checkHost(parsed.Hostname())
+}
+
+func (p SupplierURLPolicy) checkScheme(scheme string) error {
+	if len(p.AllowedSchemes) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedSchemes {
+		if strings.EqualFold(allowed, scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("scheme %q is not allowed", scheme)
+}
+
+func (p SupplierURLPolicy) checkHost(host string) error {
+	if len(p.AllowedHosts) == 0 {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix := strings.TrimPrefix(allowed, "*"); suffix != allowed {
+			if strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+				return nil
+			}
+		} else if host == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not allowed", host)
+}
+
+// validateSupplierURLs checks every supplier url against the policy
+func (p SupplierURLPolicy) validateSupplierURLs(suppliers []SupplierConfig) []string {
+	var problems []string
+	for _, supplier := range suppliers {
+		if err := p.CheckURL(supplier.URL); err != nil {
+			problems = append(problems, fmt.Sprintf("supplier %q url is not allowed: %s", supplier.Name, err))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSupplierURLPolicy_CheckURL(t *testing.T) {
+	policy := SupplierURLPolicy{
+		AllowedSchemes: []string{"https", "file"},
+		AllowedHosts:   []string{"api.supplier.com", "*.partner.net", "10.0.0.5"},
+	}
+	for _, allowed := range []string{
+		"https://api.supplier.com/hotels",
+		"https://API.Supplier.com:8443/hotels",
+		"https://feeds.partner.net/hotels",
+		"https://eu.feeds.partner.net/hotels",
+		"https://10.0.0.5/hotels",
+		"file:///data/supplierA.json",
+	} {
+		assert.Nil(t, policy.CheckURL(allowed), allowed)
+	}
+	for _, blocked := range []string{
+		"http://api.supplier.com/hotels",
+		"https://partner.net/hotels",
+		"https://api.supplier.com.evil.com/hotels",
+		"https://evilpartner.net/hotels",
+		"ftp://api.supplier.com/hotels",
+	} {
+		assert.Error(t, policy.CheckURL(blocked), blocked)
+	}
+}
+
+func TestSupplierURLPolicy_EmptyAllowsEverything(t *testing.T) {
+	assert.Nil(t, SupplierURLPolicy{}.CheckURL("http://localhost/hotels"))
+	assert.Nil(t, SupplierURLPolicy{}.CheckURL("file://dumps/*.json"))
+}
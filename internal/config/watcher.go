@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const DefaultReloadDebounce = 200 * time.Millisecond
+
+// ConfigWatcher watches the active app.<env>.env file, the suppliers file and
+// the mapping files and reloads the configuration whenever one of them changes. A new
+// configuration is only published to the change listeners if it loads and
+// validates successfully (including the validators added by other packages),
+// otherwise the error listeners are notified and the previous configuration
+// is kept
+type ConfigWatcher struct {
+	env        string
+	path       string
+	debounce   time.Duration
+	mu         sync.RWMutex
+	current    *RootConfig
+	validators []func(config ImmutableConfig) error
+	listeners  []func(previous, current ImmutableConfig)
+	onError    []func(err error)
+}
+
+func NewConfigWatcher(config *RootConfig, path string) *ConfigWatcher {
+	return &ConfigWatcher{
+		env:      config.GetAppEnv(),
+		path:     path,
+		debounce: DefaultReloadDebounce,
+		current:  config,
+	}
+}
+
+// Current returns the configuration that is currently active
+func (w *ConfigWatcher) Current() ImmutableConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// AddValidator registers a check a reloaded configuration has to pass before
+// it is published, e.g. that the adapter of every supplier is registered
+func (w *ConfigWatcher) AddValidator(validator func(config ImmutableConfig) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.validators = append(w.validators, validator)
+}
+
+// OnChange registers a listener called with the previous and the new
+// configuration after every successful reload
+func (w *ConfigWatcher) OnChange(listener func(previous, current ImmutableConfig)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, listener)
+}
+
+// OnError registers a listener called when a changed configuration is rejected
+func (w *ConfigWatcher) OnError(listener func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = append(w.onError, listener)
+}
+
+// Reload reads the configuration again and publishes it if it is valid
+func (w *ConfigWatcher) Reload() error {
+	config, err := LoadConfig(w.env, w.path)
+	if err != nil {
+		w.notifyError(err)
+		return err
+	}
+	w.mu.RLock()
+	validators := w.validators
+	w.mu.RUnlock()
+	for _, validator := range validators {
+		if err := validator(config); err != nil {
+			w.notifyError(err)
+			return err
+		}
+	}
+	w.mu.Lock()
+	previous := w.current
+	w.current = config
+	listeners := w.listeners
+	w.mu.Unlock()
+	for _, listener := range listeners {
+		listener(previous, config)
+	}
+	return nil
+}
+
+// Start blocks and watches the config files until the context is cancelled.
+// The directories of the files are watched rather than the files themselves
+// so editors and config map updates that replace the file are picked up too
+func (w *ConfigWatcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	watched, err := w.watchFiles(watcher)
+	if err != nil {
+		return err
+	}
+
+	// editors usually emit several events for a single save, so reloads
+	// are only triggered once the files have been quiet for w.debounce
+	debounce := time.NewTimer(w.debounce)
+	debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			debounce.Stop()
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if watched.matches(event.Name) && !event.Has(fsnotify.Chmod) {
+				debounce.Reset(w.debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.notifyError(err)
+		case <-debounce.C:
+			if w.Reload() == nil {
+				// the suppliers file may have moved to another location
+				watched, err = w.watchFiles(watcher)
+				if err != nil {
+					w.notifyError(err)
+				}
+			}
+		}
+	}
+}
+
+// watchedFiles holds the config files and the mapping directory whose
+// events trigger a reload
+type watchedFiles struct {
+	files       map[string]bool
+	mappingsDir string
+}
+
+func (w watchedFiles) matches(name string) bool {
+	name = filepath.Clean(name)
+	if w.files[name] {
+		return true
+	}
+	return w.mappingsDir != "" && filepath.Dir(name) == w.mappingsDir && filepath.Ext(name) == ".json"
+}
+
+// watchFiles adds the directory of every config file and the mappings
+// directory to the watcher
+func (w *ConfigWatcher) watchFiles(watcher *fsnotify.Watcher) (watchedFiles, error) {
+	w.mu.RLock()
+	files := []string{w.current.ConfigFile, w.current.SuppliersFile}
+	mappingsDir := w.current.MappingsDir
+	w.mu.RUnlock()
+	watched := watchedFiles{files: make(map[string]bool)}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		file, err := filepath.Abs(file)
+		if err != nil {
+			return watched, err
+		}
+		err = watcher.Add(filepath.Dir(file))
+		if err != nil {
+			return watched, err
+		}
+		watched.files[file] = true
+	}
+	if mappingsDir != "" {
+		dir, err := filepath.Abs(mappingsDir)
+		if err != nil {
+			return watched, err
+		}
+		// the mappings directory is optional, it is only watched if it exists
+		if watcher.Add(dir) == nil {
+			watched.mappingsDir = dir
+		}
+	}
+	return watched, nil
+}
+
+func (w *ConfigWatcher) notifyError(err error) {
+	w.mu.RLock()
+	errorListeners := w.onError
+	w.mu.RUnlock()
+	for _, listener := range errorListeners {
+		listener(err)
+	}
+}
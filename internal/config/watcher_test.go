@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startWatcher(t *testing.T, dir string) (*ConfigWatcher, func() []ImmutableConfig, func() []error) {
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	watcher := NewConfigWatcher(config, dir)
+	watcher.debounce = 10 * time.Millisecond
+	var mu sync.Mutex
+	var changes []ImmutableConfig
+	var errs []error
+	watcher.OnChange(func(previous, current ImmutableConfig) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, current)
+	})
+	watcher.OnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go watcher.Start(ctx)
+	// give the watcher some time to register the watches
+	time.Sleep(50 * time.Millisecond)
+	return watcher, func() []ImmutableConfig {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]ImmutableConfig{}, changes...)
+		}, func() []error {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]error{}, errs...)
+		}
+}
+
+func TestConfigWatcher_PublishesValidChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=warn\nSUPPLIER_CONFIG=supplierA:http://localhost/a\n")
+	watcher, changes, _ := startWatcher(t, dir)
+
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=debug\nSUPPLIER_CONFIG=supplierA:http://localhost/a\n")
+
+	assert.Eventually(t, func() bool { return len(changes()) > 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, changes()[0].GetLogLevel(), "debug")
+	assert.Equal(t, watcher.Current().GetLogLevel(), "debug")
+}
+
+func TestConfigWatcher_RejectsInvalidChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=warn\nSUPPLIER_CONFIG=supplierA:http://localhost/a\n")
+	watcher, changes, errs := startWatcher(t, dir)
+
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=debug\nSUPPLIER_CONFIG=supplierA\n")
+
+	assert.Eventually(t, func() bool { return len(errs()) > 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Empty(t, changes())
+	assert.Equal(t, watcher.Current().GetLogLevel(), "warn")
+	assert.Equal(t, watcher.Current().GetSuppliers()[0].URL, "http://localhost/a")
+}
+
+func TestConfigWatcher_RejectsChangesFailingValidators(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=warn\nSUPPLIER_CONFIG=supplierA:http://localhost/a\n")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	watcher := NewConfigWatcher(config, dir)
+	watcher.AddValidator(func(config ImmutableConfig) error {
+		if config.GetSuppliers()[0].URL == "http://localhost/b" {
+			return errors.New(`supplier "supplierA": unknown adapter`)
+		}
+		return nil
+	})
+	var previous []ImmutableConfig
+	watcher.OnChange(func(before, current ImmutableConfig) {
+		previous = append(previous, before)
+	})
+
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=debug\nSUPPLIER_CONFIG=supplierA:http://localhost/b\n")
+	assert.Error(t, watcher.Reload())
+	assert.Empty(t, previous)
+	assert.Equal(t, watcher.Current().GetLogLevel(), "warn")
+
+	// the next change is compared with the config that is actually active
+	writeEnvFile(t, dir, "local", "LOG_LEVEL=debug\nSUPPLIER_CONFIG=supplierA:http://localhost/c\n")
+	assert.Nil(t, watcher.Reload())
+	assert.Equal(t, len(previous), 1)
+	assert.Equal(t, previous[0].GetSuppliers()[0].URL, "http://localhost/a")
+}
+
+func TestConfigWatcher_ReportsSuppliersOfChangedMapping(t *testing.T) {
+	dir := t.TempDir()
+	mappingsDir := filepath.Join(dir, "mappings")
+	assert.Nil(t, os.Mkdir(mappingsDir, 0o700))
+	writeMapping := func(version, path string) {
+		content := `{"name": "supplierD", "version": "` + version + `", "fields": {"id": {"path": "` + path + `"}}}`
+		assert.Nil(t, os.WriteFile(filepath.Join(mappingsDir, "supplierD.json"), []byte(content), 0o600))
+	}
+	writeMapping("v1", "code")
+	writeEnvFile(t, dir, "local", "SUPPLIER_CONFIG=supplierA:http://localhost/a,supplierD:http://localhost/d\nMAPPINGS_DIR="+mappingsDir+"\n")
+	config, err := LoadConfig("local", dir)
+	assert.Nil(t, err)
+	watcher := NewConfigWatcher(config, dir)
+	var changed [][]string
+	watcher.OnChange(func(previous, current ImmutableConfig) {
+		changed = append(changed, ChangedConfigSuppliers(previous, current))
+	})
+
+	// only the mapping file changes, the suppliers bound with it are reloaded
+	writeMapping("v2", "hotel_code")
+	assert.Nil(t, watcher.Reload())
+	assert.Equal(t, changed, [][]string{{"supplierD"}})
+}
+
+func TestChangedSuppliers(t *testing.T) {
+	previous := []SupplierConfig{
+		{Name: "supplierA", URL: "http://localhost/a"},
+		{Name: "supplierB", URL: "http://localhost/b"},
+		{Name: "supplierC", URL: "http://localhost/c"},
+	}
+	current := []SupplierConfig{
+		{Name: "supplierA", URL: "http://localhost/a"},
+		{Name: "supplierB", URL: "http://localhost/b2"},
+		{Name: "supplierD", URL: "http://localhost/d"},
+	}
+	assert.Equal(t, ChangedSuppliers(previous, current), []string{"supplierB", "supplierD", "supplierC"})
+	assert.Empty(t, ChangedSuppliers(previous, previous))
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"datamerge/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type LoaderHandler struct {
+	scheduler   service.IDataLoaderScheduler
+	breakers    service.ISupplierBreakers
+	deadLetters repository.IDeadLetters
+	archive     service.IPayloadArchive
+	adapters    model.IHotelLoaderDataRegistry
+}
+
+func NewLoaderHandler(scheduler service.IDataLoaderScheduler, breakers service.ISupplierBreakers, deadLetters repository.IDeadLetters, archive service.IPayloadArchive, adapters model.IHotelLoaderDataRegistry) *LoaderHandler {
+	return &LoaderHandler{
+		scheduler:   scheduler,
+		breakers:    breakers,
+		deadLetters: deadLetters,
+		archive:     archive,
+		adapters:    adapters,
+	}
+}
+
+// GetLoadStatus returns the last run time and outcome of the data loader
+func (h *LoaderHandler) GetLoadStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scheduler.Status())
+}
+
+// GetBreakers returns the circuit breaker state of every supplier
+func (h *LoaderHandler) GetBreakers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.breakers.Breakers())
+}
+
+// GetDeadLetters returns the rejected supplier records, newest first. They can
+// be filtered with the supplier and run_id query parameters and limited with
+// the limit query parameter
+func (h *LoaderHandler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	filter := model.DeadLetterFilter{
+		Supplier: query.Get("supplier"),
+		RunID:    query.Get("run_id"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 0 {
+			sendErrorResponse(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.deadLetters.DeadLetters(filter))
+}
+
+// ListArchivedRuns returns the manifest of every archived load run
+func (h *LoaderHandler) ListArchivedRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	manifests, err := h.archive.ArchivedRuns()
+	if err != nil {
+		sendArchiveErrorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifests)
+}
+
+// ReplayRun replays the archived load run given by the run_id query parameter
+// and returns the load report of the replay. The current supplier adapters
+// and merge order are used when current_rules is true
+func (h *LoaderHandler) ReplayRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	runID := query.Get("run_id")
+	if runID == "" {
+		sendErrorResponse(w, "Missing run_id", http.StatusBadRequest)
+		return
+	}
+	var currentRules bool
+	if value := query.Get("current_rules"); value != "" {
+		var err error
+		currentRules, err = strconv.ParseBool(value)
+		if err != nil {
+			sendErrorResponse(w, "Invalid current_rules", http.StatusBadRequest)
+			return
+		}
+	}
+	report, err := h.archive.ReplayRun(r.Context(), runID, currentRules)
+	if err != nil {
+		sendArchiveErrorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func sendArchiveErrorResponse(w http.ResponseWriter, err error) {
+	var unknownRunErr *model.UnknownRunError
+	switch {
+	case errors.Is(err, model.ErrArchiveDisabled), errors.As(err, &unknownRunErr):
+		sendErrorResponse(w, err.Error(), http.StatusNotFound)
+	default:
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ListAdapters returns every registered supplier adapter
+func (h *LoaderHandler) ListAdapters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.adapters.List())
+}
+
+// SetupHandlers registers the admin endpoints on mux, they expose supplier
+// records and can replace the catalog so they are served by the admin
+// listener rather than along with the public endpoints
+func (h *LoaderHandler) SetupHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/loader/status", h.GetLoadStatus)
+	mux.HandleFunc("/admin/loader/breakers", h.GetBreakers)
+	mux.HandleFunc("/admin/loader/dead-letters", h.GetDeadLetters)
+	mux.HandleFunc("/admin/loader/runs", h.ListArchivedRuns)
+	mux.HandleFunc("/admin/loader/replay", h.ReplayRun)
+	mux.HandleFunc("/admin/adapters", h.ListAdapters)
+}
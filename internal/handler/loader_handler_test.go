@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"datamerge/internal/model"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type DataLoaderSchedulerMock struct {
+	mock.Mock
+}
+
+func (d *DataLoaderSchedulerMock) Status() model.LoadStatus {
+	args := d.Called()
+	return args.Get(0).(model.LoadStatus)
+}
+
+type SupplierBreakersMock struct {
+	mock.Mock
+}
+
+func (s *SupplierBreakersMock) Breakers() []model.SupplierBreakerStatus {
+	args := s.Called()
+	return args.Get(0).([]model.SupplierBreakerStatus)
+}
+
+type DeadLettersMock struct {
+	mock.Mock
+}
+
+func (d *DeadLettersMock) DeadLetters(filter model.DeadLetterFilter) []model.DeadLetter {
+	args := d.Called(filter)
+	return args.Get(0).([]model.DeadLetter)
+}
+
+type PayloadArchiveMock struct {
+	mock.Mock
+}
+
+func (p *PayloadArchiveMock) ArchivedRuns() ([]model.ArchiveManifest, error) {
+	args := p.Called()
+	return args.Get(0).([]model.ArchiveManifest), args.Error(1)
+}
+
+func (p *PayloadArchiveMock) ReplayRun(ctx context.Context, runID string, currentRules bool) (*model.LoadReport, error) {
+	args := p.Called(runID, currentRules)
+	report, _ := args.Get(0).(*model.LoadReport)
+	return report, args.Error(1)
+}
+
+func TestLoaderHandlerGetLoadStatus_withInvalidMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/admin/loader/status", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+
+	handler.GetLoadStatus(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusMethodNotAllowed)
+}
+
+func TestLoaderHandlerGetLoadStatus_returnsSchedulerStatus(t *testing.T) {
+	schedulerMock := new(DataLoaderSchedulerMock)
+	schedulerMock.On("Status").Return(model.LoadStatus{Runs: 2, LastSuccess: false, LastError: "http request error"})
+	req := httptest.NewRequest(http.MethodGet, "/admin/loader/status", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(schedulerMock, new(SupplierBreakersMock), new(DeadLettersMock), new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+
+	handler.GetLoadStatus(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	var actual model.LoadStatus
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&actual))
+	assert.Equal(t, actual.Runs, 2)
+	assert.False(t, actual.LastSuccess)
+	assert.Equal(t, actual.LastError, "http request error")
+}
+
+func TestLoaderHandlerListAdapters_returnsRegisteredAdapters(t *testing.T) {
+	registry := model.NewHotelLoaderDataRegistry()
+	registry.MustRegister("supplierA", "v1", func() model.HotelLoaderData { return &model.HotelDataLoaderSupplierA{} })
+	registry.MustRegister("supplierA", "v2", func() model.HotelLoaderData { return &model.HotelDataLoaderSupplierA{} })
+	req := httptest.NewRequest(http.MethodGet, "/admin/adapters", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), new(PayloadArchiveMock), registry)
+
+	handler.ListAdapters(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	var actual []model.AdapterInfo
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&actual))
+	assert.Equal(t, actual, []model.AdapterInfo{
+		{Name: "supplierA", Version: "v1"},
+		{Name: "supplierA", Version: "v2", Default: true},
+	})
+}
+
+func TestLoaderHandlerGetBreakers_returnsSupplierBreakers(t *testing.T) {
+	breakersMock := new(SupplierBreakersMock)
+	breakersMock.On("Breakers").Return([]model.SupplierBreakerStatus{
+		{Supplier: "supplierA", State: model.BreakerStateClosed},
+		{Supplier: "supplierB", State: model.BreakerStateOpen, ConsecutiveFailures: 5},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/admin/loader/breakers", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), breakersMock, new(DeadLettersMock), new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+
+	handler.GetBreakers(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	var actual []model.SupplierBreakerStatus
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&actual))
+	assert.Equal(t, len(actual), 2)
+	assert.Equal(t, actual[1].State, model.BreakerStateOpen)
+	assert.Equal(t, actual[1].ConsecutiveFailures, 5)
+}
+
+func TestLoaderHandlerGetDeadLetters_returnsFilteredDeadLetters(t *testing.T) {
+	deadLettersMock := new(DeadLettersMock)
+	deadLettersMock.On("DeadLetters", model.DeadLetterFilter{Supplier: "supplierA", RunID: "run-1", Limit: 10}).Return([]model.DeadLetter{
+		{RunID: "run-1", Supplier: "supplierA", Reason: "missing hotel id", Record: `{"Name": "Radisson"}`},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/admin/loader/dead-letters?supplier=supplierA&run_id=run-1&limit=10", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), deadLettersMock, new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+
+	handler.GetDeadLetters(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	var actual []model.DeadLetter
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&actual))
+	assert.Equal(t, len(actual), 1)
+	assert.Equal(t, actual[0].Record, `{"Name": "Radisson"}`)
+	deadLettersMock.AssertExpectations(t)
+}
+
+func TestLoaderHandlerGetDeadLetters_withInvalidLimit(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/loader/dead-letters?limit=all", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+
+	handler.GetDeadLetters(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestLoaderHandlerReplayRun_returnsReplayReport(t *testing.T) {
+	archiveMock := new(PayloadArchiveMock)
+	archiveMock.On("ReplayRun", "20240501T101500Z-9f2c41d0", true).Return(&model.LoadReport{RunID: "20240502T080000Z-0a1b2c3d", ReplayOf: "20240501T101500Z-9f2c41d0", HotelsLoaded: 3}, nil)
+	req := httptest.NewRequest(http.MethodPost, "/admin/loader/replay?run_id=20240501T101500Z-9f2c41d0&current_rules=true", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), archiveMock, model.NewHotelLoaderDataRegistry())
+
+	handler.ReplayRun(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	var actual model.LoadReport
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(&actual))
+	assert.Equal(t, actual.ReplayOf, "20240501T101500Z-9f2c41d0")
+	assert.Equal(t, actual.HotelsLoaded, 3)
+	archiveMock.AssertExpectations(t)
+}
+
+func TestLoaderHandlerReplayRun_withUnknownRun(t *testing.T) {
+	archiveMock := new(PayloadArchiveMock)
+	archiveMock.On("ReplayRun", "unknown", false).Return(nil, &model.UnknownRunError{RunID: "unknown"})
+	req := httptest.NewRequest(http.MethodPost, "/admin/loader/replay?run_id=unknown", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), archiveMock, model.NewHotelLoaderDataRegistry())
+
+	handler.ReplayRun(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
+
+func TestLoaderHandlerReplayRun_withInvalidRequest(t *testing.T) {
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+	for _, target := range []string{"/admin/loader/replay", "/admin/loader/replay?run_id=run&current_rules=maybe"} {
+		rr := httptest.NewRecorder()
+		handler.ReplayRun(rr, httptest.NewRequest(http.MethodPost, target, nil))
+		assert.Equal(t, rr.Code, http.StatusBadRequest)
+	}
+	rr := httptest.NewRecorder()
+	handler.ReplayRun(rr, httptest.NewRequest(http.MethodGet, "/admin/loader/replay?run_id=run", nil))
+	assert.Equal(t, rr.Code, http.StatusMethodNotAllowed)
+}
+
+func TestLoaderHandlerListArchivedRuns_withDisabledArchive(t *testing.T) {
+	archiveMock := new(PayloadArchiveMock)
+	archiveMock.On("ArchivedRuns").Return([]model.ArchiveManifest(nil), model.ErrArchiveDisabled)
+	req := httptest.NewRequest(http.MethodGet, "/admin/loader/runs", nil)
+	rr := httptest.NewRecorder()
+	handler := NewLoaderHandler(new(DataLoaderSchedulerMock), new(SupplierBreakersMock), new(DeadLettersMock), archiveMock, model.NewHotelLoaderDataRegistry())
+
+	handler.ListArchivedRuns(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
+
+func TestLoaderHandlerSetupHandlers_registersOnAdminMuxOnly(t *testing.T) {
+	schedulerMock := new(DataLoaderSchedulerMock)
+	schedulerMock.On("Status").Return(model.LoadStatus{Runs: 1})
+	handler := NewLoaderHandler(schedulerMock, new(SupplierBreakersMock), new(DeadLettersMock), new(PayloadArchiveMock), model.NewHotelLoaderDataRegistry())
+	adminMux := http.NewServeMux()
+
+	handler.SetupHandlers(adminMux)
+
+	rr := httptest.NewRecorder()
+	adminMux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/loader/status", nil))
+	assert.Equal(t, rr.Code, http.StatusOK)
+	rr = httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/loader/replay?run_id=20240501T101500Z-9f2c41d0", nil))
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
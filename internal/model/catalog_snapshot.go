@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// CatalogSnapshotVersion is the version of the catalog snapshot format
+// written by the application, snapshots of another version are not loaded
+const CatalogSnapshotVersion = 1
+
+// CatalogSnapshot is a copy of the merged catalog persisted to disk, so the
+// last good catalog can be served on startup before the suppliers are loaded
+// RunID is the load run the catalog was merged by and Checksum the hex
+// SHA-256 of the JSON encoded hotels
+type CatalogSnapshot struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	RunID      string    `json:"run_id"`
+	HotelCount int       `json:"hotel_count"`
+	Checksum   string    `json:"checksum"`
+	Hotels     []*Hotel  `json:"hotels"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// DeadLetter is a supplier record rejected by the loader, kept so it can be
+// inspected and reported back to the supplier. Record is the raw record as
+// received, truncated when it is larger than the dead letter store allows
+type DeadLetter struct {
+	RunID      string    `json:"run_id"`
+	Supplier   string    `json:"supplier"`
+	Location   string    `json:"location"`
+	Reason     string    `json:"reason"`
+	Record     string    `json:"record"`
+	Truncated  bool      `json:"truncated,omitempty"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+// DeadLetterFilter selects dead letters, empty fields match every dead letter
+// and a zero Limit returns every match
+type DeadLetterFilter struct {
+	Supplier string
+	RunID    string
+	Limit    int
+}
+
+func (f DeadLetterFilter) Matches(deadLetter DeadLetter) bool {
+	return (f.Supplier == "" || f.Supplier == deadLetter.Supplier) &&
+		(f.RunID == "" || f.RunID == deadLetter.RunID)
+}
@@ -1,17 +1,229 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HttpError is returned when a supplier payload could not be fetched
+// StatusCode is set when the supplier answered with a non-2xx status,
+// otherwise Err holds the underlying transport error
 type HttpError struct {
+	URL        string
+	StatusCode int
+	Err        error
 }
 
 func (h *HttpError) Error() string {
-	return "http request error"
+	msg := "http request error"
+	if h.StatusCode != 0 {
+		msg = fmt.Sprintf("%s: unexpected status code %d", msg, h.StatusCode)
+	}
+	if h.Err != nil {
+		msg = fmt.Sprintf("%s: %s", msg, h.Err.Error())
+	}
+	return msg
+}
+
+func (h *HttpError) Unwrap() error {
+	return h.Err
 }
 
 type JsonError struct {
+	Err error
 }
 
 func (j *JsonError) Error() string {
-	return "json deserialization error during decoding response"
+	msg := "json deserialization error during decoding response"
+	if j.Err != nil {
+		msg = fmt.Sprintf("%s: %s", msg, j.Err.Error())
+	}
+	return msg
+}
+
+func (j *JsonError) Unwrap() error {
+	return j.Err
+}
+
+// CsvError is returned when a CSV supplier feed or one of its rows cannot be read
+type CsvError struct {
+	Err error
+}
+
+func (c *CsvError) Error() string {
+	return fmt.Sprintf("csv error during decoding response: %s", c.Err.Error())
+}
+
+func (c *CsvError) Unwrap() error {
+	return c.Err
+}
+
+// XmlError is returned when an XML supplier feed cannot be read
+type XmlError struct {
+	Err error
+}
+
+func (x *XmlError) Error() string {
+	return fmt.Sprintf("xml error during decoding response: %s", x.Err.Error())
+}
+
+func (x *XmlError) Unwrap() error {
+	return x.Err
+}
+
+// FileError is returned when a supplier payload could not be read from a
+// file:// source, Path is the file, directory or pattern that failed
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (f *FileError) Error() string {
+	return fmt.Sprintf("file source error: %s: %s", f.Path, f.Err.Error())
+}
+
+func (f *FileError) Unwrap() error {
+	return f.Err
+}
+
+// PageError is returned when a page of a paginated supplier cannot be loaded,
+// URL is the redacted url of the page
+type PageError struct {
+	Page int
+	URL  string
+	Err  error
+}
+
+func (p *PageError) Error() string {
+	return fmt.Sprintf("page %d: %s", p.Page, p.Err.Error())
+}
+
+func (p *PageError) Unwrap() error {
+	return p.Err
+}
+
+// CircuitOpenError is returned for a supplier that is not fetched because its
+// circuit breaker opened after too many consecutive failures, the supplier is
+// fetched again from RetryAt on
+type CircuitOpenError struct {
+	Failures int
+	RetryAt  time.Time
+}
+
+func (c *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open after %d consecutive failures, retrying from %s",
+		c.Failures, c.RetryAt.Format(time.RFC3339))
+}
+
+// LimitError is returned when a supplier fetch is aborted because the supplier
+// exceeds one of its payload limits, Limit is the name of the exceeded limit
+type LimitError struct {
+	Limit string
+	Max   int64
+}
+
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("supplier exceeds its %s limit of %d", l.Limit, l.Max)
+}
+
+// URLPolicyError is returned when a supplier url, a redirect or the address
+// a supplier host resolves to is not allowed by the supplier url policy
+type URLPolicyError struct {
+	Err error
+}
+
+func (u *URLPolicyError) Error() string {
+	return fmt.Sprintf("supplier url not allowed: %s", u.Err.Error())
+}
+
+func (u *URLPolicyError) Unwrap() error {
+	return u.Err
+}
+
+// UnknownAdapterError is returned when a supplier refers to an adapter
+// that is not registered
+type UnknownAdapterError struct {
+	Adapter string
+}
+
+func (u *UnknownAdapterError) Error() string {
+	return fmt.Sprintf("unknown supplier adapter %q", u.Adapter)
+}
+
+// ErrArchiveDisabled is returned when a load run is replayed while the
+// payload archive is disabled
+var ErrArchiveDisabled = errors.New("payload archive is disabled")
+
+// UnknownRunError is returned when a load run to replay is not archived
+type UnknownRunError struct {
+	RunID string
+}
+
+func (u *UnknownRunError) Error() string {
+	return fmt.Sprintf("load run %q is not archived", u.RunID)
+}
+
+// ArchiveError is returned when an archived payload cannot be replayed, e.g.
+// because it no longer matches its checksum
+type ArchiveError struct {
+	Path string
+	Err  error
+}
+
+func (a *ArchiveError) Error() string {
+	return fmt.Sprintf("archived payload %s: %s", a.Path, a.Err.Error())
+}
+
+func (a *ArchiveError) Unwrap() error {
+	return a.Err
+}
+
+// SnapshotError is returned when a catalog snapshot cannot be read, e.g.
+// because it is truncated or no longer matches its checksum
+type SnapshotError struct {
+	Name string
+	Err  error
+}
+
+func (s *SnapshotError) Error() string {
+	return fmt.Sprintf("catalog snapshot %s: %s", s.Name, s.Err.Error())
+}
+
+func (s *SnapshotError) Unwrap() error {
+	return s.Err
+}
+
+// LoadError is returned by the data loader when none of the suppliers could
+// be loaded, Errs holds the error of every supplier in configuration order
+type LoadError struct {
+	Errs []error
+}
+
+func (l *LoadError) Error() string {
+	msgs := make([]string, 0, len(l.Errs))
+	for _, err := range l.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("no supplier could be loaded: %s", strings.Join(msgs, "; "))
+}
+
+// IncompleteCatalogError is returned by the data loader when a load would
+// drop suppliers of a catalog restored from a snapshot, because they could
+// not be loaded and have no previous data to fall back on. The restored
+// catalog is kept then
+type IncompleteCatalogError struct {
+	Suppliers []string
+	Errs      []error
+}
+
+func (i *IncompleteCatalogError) Error() string {
+	msgs := make([]string, 0, len(i.Errs))
+	for _, err := range i.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("restored catalog kept, suppliers %s could not be loaded: %s", strings.Join(i.Suppliers, ", "), strings.Join(msgs, "; "))
 }
 
 // ErrorResponse will be used to wrap errors into a JSON response to send back to client
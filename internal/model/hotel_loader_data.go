@@ -1,6 +1,6 @@
 package model
 
-import "fmt"
+import "encoding/json"
 
 type HotelLoaderData interface {
 	GetId() string
@@ -14,17 +14,22 @@ type HotelLoaderData interface {
 	ConvertToHotelLoaderData(t interface{}) (HotelLoaderData, error)
 }
 
-type HotelLoaderDataFactory struct{}
+// HotelLoaderDataDecoder is implemented by the adapters that can bind a raw
+// JSON record directly, without decoding it to a generic value first
+type HotelLoaderDataDecoder interface {
+	DecodeHotelLoaderData(data []byte) (HotelLoaderData, error)
+}
 
-func (sf HotelLoaderDataFactory) CreateSupplier(supplierType string) HotelLoaderData {
-	switch supplierType {
-	case "supplierA":
-		return &HotelDataLoaderSupplierA{}
-	case "supplierB":
-		return &HotelDataLoaderSupplierB{}
-	case "supplierC":
-		return &HotelDataLoaderSupplierC{}
-	default:
-		panic(fmt.Sprintf("Invalid supplier type: %s", supplierType))
+// DecodeHotelLoaderData binds a raw JSON record to the given adapter, adapters
+// that don't implement HotelLoaderDataDecoder go through ConvertToHotelLoaderData
+func DecodeHotelLoaderData(adapter HotelLoaderData, data []byte) (HotelLoaderData, error) {
+	if decoder, ok := adapter.(HotelLoaderDataDecoder); ok {
+		return decoder.DecodeHotelLoaderData(data)
+	}
+	var record interface{}
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		return nil, err
 	}
+	return adapter.ConvertToHotelLoaderData(record)
 }
@@ -0,0 +1,57 @@
+package model
+
+// HotelDataLoaderMapped is a generic adapter of the HotelLoaderData whose
+// binding to the supplier records is declared in a HotelMapping instead of
+// a hand-written struct, so onboarding a supplier with a plain JSON feed
+// only requires a mapping file
+type HotelDataLoaderMapped struct {
+	mapping *HotelMapping
+	hotel   Hotel
+}
+
+func NewHotelDataLoaderMapped(mapping *HotelMapping) *HotelDataLoaderMapped {
+	return &HotelDataLoaderMapped{mapping: mapping}
+}
+
+func (h *HotelDataLoaderMapped) ConvertToHotelLoaderData(t interface{}) (HotelLoaderData, error) {
+	hotel, err := h.mapping.Apply(t)
+	if err != nil {
+		return nil, err
+	}
+	return &HotelDataLoaderMapped{mapping: h.mapping, hotel: hotel}, nil
+}
+
+func (h *HotelDataLoaderMapped) GetId() string {
+	return h.hotel.ID
+}
+
+func (h *HotelDataLoaderMapped) GetDestinationId() int {
+	return h.hotel.DestinationID
+}
+
+func (h *HotelDataLoaderMapped) GetName() string {
+	return h.hotel.Name
+}
+
+func (h *HotelDataLoaderMapped) GetLocation() HotelLocation {
+	return h.hotel.Location
+}
+
+func (h *HotelDataLoaderMapped) GetDescription() string {
+	return h.hotel.Description
+}
+
+func (h *HotelDataLoaderMapped) GetAmenities() HotelAmenities {
+	return h.hotel.Amenities
+}
+
+func (h *HotelDataLoaderMapped) GetImages() HotelImages {
+	return h.hotel.Images
+}
+
+func (h *HotelDataLoaderMapped) GetBookingConditions() []string {
+	if h.hotel.BookingConditions == nil {
+		return []string{}
+	}
+	return h.hotel.BookingConditions
+}
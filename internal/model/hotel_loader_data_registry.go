@@ -0,0 +1,188 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AdapterVersionSeparator separates the adapter name from its version when
+// looking up a specific adapter version, e.g. supplierA@v1
+const AdapterVersionSeparator = "@"
+
+// HotelLoaderDataConstructor returns a new, empty instance of an adapter
+type HotelLoaderDataConstructor func() HotelLoaderData
+
+// AdapterInfo describes a registered adapter for diagnostics
+type AdapterInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Default bool   `json:"default"`
+}
+
+type IHotelLoaderDataRegistry interface {
+	Lookup(adapter string) (HotelLoaderData, error)
+	List() []AdapterInfo
+}
+
+// HotelLoaderDataRegistry keeps every HotelLoaderData adapter by name and
+// version. Adapters register themselves (usually from an init function) so
+// adding a supplier does not require changing the loader.
+// Looking up a name without a version returns the last registered version
+type HotelLoaderDataRegistry struct {
+	mu             sync.RWMutex
+	adapters       map[string]map[string]HotelLoaderDataConstructor
+	defaultVersion map[string]string
+	// mappings keeps the mapping registered under every name@version
+	mappings map[string]*HotelMapping
+}
+
+// DefaultHotelLoaderDataRegistry holds the adapters shipped with the application
+var DefaultHotelLoaderDataRegistry = NewHotelLoaderDataRegistry()
+
+func NewHotelLoaderDataRegistry() *HotelLoaderDataRegistry {
+	return &HotelLoaderDataRegistry{
+		adapters:       make(map[string]map[string]HotelLoaderDataConstructor),
+		defaultVersion: make(map[string]string),
+		mappings:       make(map[string]*HotelMapping),
+	}
+}
+
+// Register adds an adapter under the given name and version, registering
+// the same name and version twice is an error
+func (r *HotelLoaderDataRegistry) Register(name, version string, constructor HotelLoaderDataConstructor) error {
+	if name == "" || strings.Contains(name, AdapterVersionSeparator) {
+		return fmt.Errorf("invalid adapter name %q", name)
+	}
+	if strings.Contains(version, AdapterVersionSeparator) {
+		return fmt.Errorf("invalid version %q for adapter %q", version, name)
+	}
+	if constructor == nil {
+		return fmt.Errorf("adapter %q has no constructor", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions, present := r.adapters[name]
+	if !present {
+		versions = make(map[string]HotelLoaderDataConstructor)
+		r.adapters[name] = versions
+	}
+	if _, present := versions[version]; present {
+		return fmt.Errorf("adapter %q version %q is already registered", name, version)
+	}
+	versions[version] = constructor
+	r.defaultVersion[name] = version
+	return nil
+}
+
+// MustRegister is like Register but panics on error, it is meant to be
+// called from the init function of an adapter
+func (r *HotelLoaderDataRegistry) MustRegister(name, version string, constructor HotelLoaderDataConstructor) {
+	err := r.Register(name, version, constructor)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegisterMappings registers a HotelDataLoaderMapped adapter for every mapping
+// under the mapping name and version. Mappings already registered with the
+// same content are skipped, a registered name and version is never replaced
+// so registering it with a changed content is an error (it needs a new version)
+func (r *HotelLoaderDataRegistry) RegisterMappings(mappings []*HotelMapping) error {
+	for _, mapping := range mappings {
+		err := mapping.Validate()
+		if err != nil {
+			return err
+		}
+		adapter := mapping.Name + AdapterVersionSeparator + mapping.GetVersion()
+		r.mu.RLock()
+		registered, present := r.mappings[adapter]
+		r.mu.RUnlock()
+		if present {
+			if !registered.Equal(mapping) {
+				return fmt.Errorf("mapping %q version %q is already registered with a different content, change its version", mapping.Name, mapping.GetVersion())
+			}
+			continue
+		}
+		mapping := mapping
+		err = r.Register(mapping.Name, mapping.GetVersion(), func() HotelLoaderData {
+			return NewHotelDataLoaderMapped(mapping)
+		})
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.mappings[adapter] = mapping
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// Clone returns a copy of the registry, registering adapters in the copy does
+// not change the registry. It is used to check a change before applying it
+func (r *HotelLoaderDataRegistry) Clone() *HotelLoaderDataRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clone := NewHotelLoaderDataRegistry()
+	for name, versions := range r.adapters {
+		clone.adapters[name] = make(map[string]HotelLoaderDataConstructor, len(versions))
+		for version, constructor := range versions {
+			clone.adapters[name][version] = constructor
+		}
+		clone.defaultVersion[name] = r.defaultVersion[name]
+	}
+	for adapter, mapping := range r.mappings {
+		clone.mappings[adapter] = mapping
+	}
+	return clone
+}
+
+// IsRegistered reports whether the adapter (name or name@version) is registered
+func (r *HotelLoaderDataRegistry) IsRegistered(adapter string) bool {
+	_, err := r.Lookup(adapter)
+	return err == nil
+}
+
+// Lookup returns a new instance of the adapter, the adapter is either a name
+// (using its default version) or name@version
+func (r *HotelLoaderDataRegistry) Lookup(adapter string) (HotelLoaderData, error) {
+	name, version, hasVersion := strings.Cut(adapter, AdapterVersionSeparator)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions, present := r.adapters[name]
+	if !present {
+		return nil, &UnknownAdapterError{Adapter: adapter}
+	}
+	if !hasVersion {
+		version = r.defaultVersion[name]
+	}
+	constructor, present := versions[version]
+	if !present {
+		return nil, &UnknownAdapterError{Adapter: adapter}
+	}
+	return constructor(), nil
+}
+
+// List returns every registered adapter sorted by name and version
+func (r *HotelLoaderDataRegistry) List() []AdapterInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]AdapterInfo, 0)
+	for name, versions := range r.adapters {
+		for version := range versions {
+			result = append(result, AdapterInfo{
+				Name:    name,
+				Version: version,
+				Default: r.defaultVersion[name] == version,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHotelLoaderDataRegistry_LookupDefaultAndSpecificVersion(t *testing.T) {
+	registry := NewHotelLoaderDataRegistry()
+	registry.MustRegister("supplier", "v1", func() HotelLoaderData { return &HotelDataLoaderSupplierA{} })
+	registry.MustRegister("supplier", "v2", func() HotelLoaderData { return &HotelDataLoaderSupplierB{} })
+
+	adapter, err := registry.Lookup("supplier")
+	assert.Nil(t, err)
+	assert.IsType(t, adapter, &HotelDataLoaderSupplierB{})
+
+	adapter, err = registry.Lookup("supplier@v1")
+	assert.Nil(t, err)
+	assert.IsType(t, adapter, &HotelDataLoaderSupplierA{})
+}
+
+func TestHotelLoaderDataRegistry_LookupUnknownAdapterReturnsError(t *testing.T) {
+	registry := NewHotelLoaderDataRegistry()
+	registry.MustRegister("supplier", "v1", func() HotelLoaderData { return &HotelDataLoaderSupplierA{} })
+
+	_, err := registry.Lookup("unknown")
+	assert.IsType(t, err, &UnknownAdapterError{})
+	_, err = registry.Lookup("supplier@v3")
+	assert.IsType(t, err, &UnknownAdapterError{})
+}
+
+func TestHotelLoaderDataRegistry_RegisterTwiceReturnsError(t *testing.T) {
+	registry := NewHotelLoaderDataRegistry()
+	constructor := func() HotelLoaderData { return &HotelDataLoaderSupplierA{} }
+	assert.Nil(t, registry.Register("supplier", "v1", constructor))
+	assert.Error(t, registry.Register("supplier", "v1", constructor))
+	assert.Error(t, registry.Register("supplier@v2", "", constructor))
+}
+
+func TestHotelLoaderDataRegistry_CloneLeavesRegistryUnchanged(t *testing.T) {
+	registry := NewHotelLoaderDataRegistry()
+	constructor := func() HotelLoaderData { return &HotelDataLoaderSupplierA{} }
+	assert.Nil(t, registry.Register("supplier", "v1", constructor))
+	clone := registry.Clone()
+	assert.Nil(t, clone.Register("supplier", "v2", constructor))
+
+	assert.True(t, clone.IsRegistered("supplier@v1"))
+	assert.Equal(t, len(clone.List()), 2)
+	assert.False(t, registry.IsRegistered("supplier@v2"))
+	assert.Equal(t, registry.List(), []AdapterInfo{{Name: "supplier", Version: "v1", Default: true}})
+}
+
+func TestHotelLoaderDataRegistry_RegisterMappingsRefusesChangedMapping(t *testing.T) {
+	registry := NewHotelLoaderDataRegistry()
+	mapping := func(path string) *HotelMapping {
+		return &HotelMapping{Name: "supplierD", Version: "v1", Fields: map[string]FieldMapping{
+			MappingFieldId: {Path: path},
+		}}
+	}
+	assert.Nil(t, registry.RegisterMappings([]*HotelMapping{mapping("code")}))
+	assert.Nil(t, registry.RegisterMappings([]*HotelMapping{mapping("code")}))
+
+	err := registry.RegisterMappings([]*HotelMapping{mapping("hotel_code")})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `mapping "supplierD" version "v1" is already registered with a different content`)
+
+	changed := mapping("hotel_code")
+	changed.Version = "v2"
+	assert.Nil(t, registry.RegisterMappings([]*HotelMapping{changed}))
+	assert.Equal(t, registry.List(), []AdapterInfo{
+		{Name: "supplierD", Version: "v1", Default: false},
+		{Name: "supplierD", Version: "v2", Default: true},
+	})
+}
+
+func TestDefaultHotelLoaderDataRegistry_HasBuiltInSuppliers(t *testing.T) {
+	for _, supplier := range []string{"supplierA", "supplierB", "supplierC"} {
+		_, err := DefaultHotelLoaderDataRegistry.Lookup(supplier)
+		assert.Nil(t, err)
+	}
+}
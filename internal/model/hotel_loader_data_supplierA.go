@@ -28,13 +28,23 @@ type HotelDataLoaderSupplierA struct {
 	Facilities    []string    `json:"Facilities"`
 }
 
+func init() {
+	DefaultHotelLoaderDataRegistry.MustRegister("supplierA", "v1", func() HotelLoaderData {
+		return &HotelDataLoaderSupplierA{}
+	})
+}
+
 func (h *HotelDataLoaderSupplierA) ConvertToHotelLoaderData(t interface{}) (HotelLoaderData, error) {
 	jsonBytes, err := json.Marshal(t)
 	if err != nil {
 		return nil, err
 	}
+	return h.DecodeHotelLoaderData(jsonBytes)
+}
+
+func (h *HotelDataLoaderSupplierA) DecodeHotelLoaderData(data []byte) (HotelLoaderData, error) {
 	var result HotelDataLoaderSupplierA
-	err = json.Unmarshal(jsonBytes, &result)
+	err := json.Unmarshal(data, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -39,13 +39,23 @@ type ImageSupplierB struct {
 	Caption string `json:"caption"`
 }
 
+func init() {
+	DefaultHotelLoaderDataRegistry.MustRegister("supplierB", "v1", func() HotelLoaderData {
+		return &HotelDataLoaderSupplierB{}
+	})
+}
+
 func (h *HotelDataLoaderSupplierB) ConvertToHotelLoaderData(t interface{}) (HotelLoaderData, error) {
 	jsonBytes, err := json.Marshal(t)
 	if err != nil {
 		return nil, err
 	}
+	return h.DecodeHotelLoaderData(jsonBytes)
+}
+
+func (h *HotelDataLoaderSupplierB) DecodeHotelLoaderData(data []byte) (HotelLoaderData, error) {
 	var result HotelDataLoaderSupplierB
-	err = json.Unmarshal(jsonBytes, &result)
+	err := json.Unmarshal(data, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -24,13 +24,23 @@ type ImageSupplierC struct {
 	Description string `json:"description"`
 }
 
+func init() {
+	DefaultHotelLoaderDataRegistry.MustRegister("supplierC", "v1", func() HotelLoaderData {
+		return &HotelDataLoaderSupplierC{}
+	})
+}
+
 func (h *HotelDataLoaderSupplierC) ConvertToHotelLoaderData(t interface{}) (HotelLoaderData, error) {
 	jsonBytes, err := json.Marshal(t)
 	if err != nil {
 		return nil, err
 	}
+	return h.DecodeHotelLoaderData(jsonBytes)
+}
+
+func (h *HotelDataLoaderSupplierC) DecodeHotelLoaderData(data []byte) (HotelLoaderData, error) {
 	var result HotelDataLoaderSupplierC
-	err = json.Unmarshal(jsonBytes, &result)
+	err := json.Unmarshal(data, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// Keys used for the attributes and the text of an XML element once it is
+// converted to a generic record, e.g. <Hotel id="iJhz">Villas</Hotel> becomes
+// {"@id": "iJhz", "#text": "Villas"}
+const (
+	XmlAttributePrefix = "@"
+	XmlTextKey         = "#text"
+)
+
+// HotelLoaderDataXmlDecoder is implemented by the adapters binding an XML
+// record element directly, e.g. through encoding/xml struct tags
+type HotelLoaderDataXmlDecoder interface {
+	DecodeHotelLoaderDataXml(data []byte) (HotelLoaderData, error)
+}
+
+// DecodeHotelLoaderDataXml binds a raw XML record element to the given adapter
+// Adapters that don't implement HotelLoaderDataXmlDecoder get the element
+// converted to a generic record through ConvertToHotelLoaderData, so mapping
+// adapters can bind XML feeds as well
+func DecodeHotelLoaderDataXml(adapter HotelLoaderData, data []byte) (HotelLoaderData, error) {
+	if decoder, ok := adapter.(HotelLoaderDataXmlDecoder); ok {
+		return decoder.DecodeHotelLoaderDataXml(data)
+	}
+	record, err := DecodeXmlRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.ConvertToHotelLoaderData(record)
+}
+
+// DecodeXmlRecord converts an XML element to the generic value decoded JSON
+// records have. An element without attributes and child elements becomes its
+// trimmed text, any other element an object keyed by the child element names,
+// attributes being prefixed with XmlAttributePrefix and text stored under
+// XmlTextKey. Child elements occurring more than once become a list
+func DecodeXmlRecord(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return decodeXmlElement(decoder, start)
+		}
+	}
+}
+
+func decodeXmlElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		fields[XmlAttributePrefix+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXmlElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := fields[name].(type) {
+			case nil:
+				fields[name] = child
+			case []interface{}:
+				fields[name] = append(existing, child)
+			default:
+				fields[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			if len(fields) == 0 {
+				return value, nil
+			}
+			if value != "" {
+				fields[XmlTextKey] = value
+			}
+			return fields, nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodeXmlRecord(t *testing.T) {
+	record, err := DecodeXmlRecord([]byte(`<Hotel code="iJhz">
+		<Name> Beach Villas </Name>
+		<Facility>Pool</Facility>
+		<Facility>WiFi</Facility>
+		<Image url="url1">Double room</Image>
+		<Empty/>
+	</Hotel>`))
+	assert.Nil(t, err)
+	assert.Equal(t, record, map[string]interface{}{
+		"@code":    "iJhz",
+		"Name":     "Beach Villas",
+		"Facility": []interface{}{"Pool", "WiFi"},
+		"Image":    map[string]interface{}{"@url": "url1", "#text": "Double room"},
+		"Empty":    "",
+	})
+}
+
+func TestDecodeHotelLoaderDataXml_WithMappingAdapter(t *testing.T) {
+	mapping := HotelMapping{
+		Name: "supplierX",
+		Fields: map[string]FieldMapping{
+			MappingFieldId:               {Path: "@code"},
+			MappingFieldDestinationId:    {Path: "Destination"},
+			MappingFieldGeneralAmenities: {Path: "Facility[*]"},
+			MappingFieldRoomImages:       {Path: "Image[*]", Link: "@url", Description: "#text"},
+		},
+	}
+	assert.Nil(t, mapping.Validate())
+	hotel, err := DecodeHotelLoaderDataXml(NewHotelDataLoaderMapped(&mapping), []byte(`<Hotel code="iJhz">
+		<Destination>5432</Destination>
+		<Facility>Pool</Facility>
+		<Image url="url1">Double room</Image>
+	</Hotel>`))
+	assert.Nil(t, err)
+	assert.Equal(t, hotel.GetId(), "iJhz")
+	assert.Equal(t, hotel.GetDestinationId(), 5432)
+	assert.Equal(t, hotel.GetAmenities().General, []string{"Pool"})
+	assert.Equal(t, hotel.GetImages().Rooms, []Image{{Link: "url1", Description: "Double room"}})
+
+	_, err = DecodeHotelLoaderDataXml(NewHotelDataLoaderMapped(&mapping), []byte(`<Hotel><Destination>5432</Destination></Hotel>`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,271 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Hotel fields that can be the target of a FieldMapping
+const (
+	MappingFieldId                = "id"
+	MappingFieldDestinationId     = "destination_id"
+	MappingFieldName              = "name"
+	MappingFieldDescription       = "description"
+	MappingFieldLat               = "location.lat"
+	MappingFieldLng               = "location.lng"
+	MappingFieldAddress           = "location.address"
+	MappingFieldCity              = "location.city"
+	MappingFieldCountry           = "location.country"
+	MappingFieldGeneralAmenities  = "amenities.general"
+	MappingFieldRoomAmenities     = "amenities.room"
+	MappingFieldRoomImages        = "images.rooms"
+	MappingFieldSiteImages        = "images.site"
+	MappingFieldAmenitiesImages   = "images.amenities"
+	MappingFieldBookingConditions = "booking_conditions"
+
+	DefaultMappingVersion = "mapping"
+)
+
+var mappingFields = map[string]bool{
+	MappingFieldId: true, MappingFieldDestinationId: true, MappingFieldName: true,
+	MappingFieldDescription: true, MappingFieldLat: true, MappingFieldLng: true,
+	MappingFieldAddress: true, MappingFieldCity: true, MappingFieldCountry: true,
+	MappingFieldGeneralAmenities: true, MappingFieldRoomAmenities: true,
+	MappingFieldRoomImages: true, MappingFieldSiteImages: true, MappingFieldAmenitiesImages: true,
+	MappingFieldBookingConditions: true,
+}
+
+// HotelMapping declares how the records of a supplier feed are mapped to a
+// Hotel, Fields is keyed by the target Hotel field (e.g. location.city)
+type HotelMapping struct {
+	Name    string                  `json:"name"`
+	Version string                  `json:"version"`
+	Fields  map[string]FieldMapping `json:"fields"`
+	// compiled holds the parsed paths and transforms of every field, it is
+	// set once the mapping is validated
+	compiled map[string]compiledFieldMapping
+}
+
+// compiledFieldMapping is a FieldMapping with its paths and transforms parsed
+type compiledFieldMapping struct {
+	path        []mappingPathSegment
+	link        []mappingPathSegment
+	description []mappingPathSegment
+	transforms  []mappingTransform
+}
+
+// FieldMapping maps a value of the supplier record to a Hotel field
+// Path is a dot separated path in the record, array elements are selected
+// with [index] or every element with [*], e.g. images.rooms[*].url
+// Transforms are applied in order on the value (on every element of a list):
+// trim, lower, upper, title, split_camel_case and split:<separator>
+// For image fields Path selects the list of images and Link/Description are
+// the paths of the url and caption relative to every image (a list of plain
+// strings is used as links)
+type FieldMapping struct {
+	Path        string   `json:"path"`
+	Transforms  []string `json:"transforms"`
+	Link        string   `json:"link"`
+	Description string   `json:"description"`
+}
+
+// GetVersion returns the mapping version, defaulting to DefaultMappingVersion
+func (m *HotelMapping) GetVersion() string {
+	if m.Version == "" {
+		return DefaultMappingVersion
+	}
+	return m.Version
+}
+
+// Equal reports whether both mappings declare the same name, version and fields
+func (m *HotelMapping) Equal(other *HotelMapping) bool {
+	return other != nil && m.Name == other.Name && m.GetVersion() == other.GetVersion() && reflect.DeepEqual(m.Fields, other.Fields)
+}
+
+// Validate checks the mapping targets known fields with valid paths and
+// transforms, and compiles them so they are parsed once and not for every
+// record. A mapping is only applied once validated, and validated only once
+func (m *HotelMapping) Validate() error {
+	if m.compiled != nil {
+		return nil
+	}
+	var problems []string
+	if m.Name == "" {
+		problems = append(problems, "mapping has no name")
+	}
+	if _, present := m.Fields[MappingFieldId]; !present {
+		problems = append(problems, "id field is not mapped")
+	}
+	fields := make([]string, 0, len(m.Fields))
+	for field := range m.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	compiled := make(map[string]compiledFieldMapping, len(fields))
+	for _, field := range fields {
+		fieldMapping := m.Fields[field]
+		if !mappingFields[field] {
+			problems = append(problems, fmt.Sprintf("unknown field %q", field))
+			continue
+		}
+		var compiledField compiledFieldMapping
+		var err error
+		if compiledField.path, err = parseMappingPath(fieldMapping.Path); err != nil {
+			problems = append(problems, fmt.Sprintf("field %q: %s", field, err))
+		}
+		// link and description are optional, they only apply to images
+		if fieldMapping.Link != "" {
+			if compiledField.link, err = parseMappingPath(fieldMapping.Link); err != nil {
+				problems = append(problems, fmt.Sprintf("field %q link: %s", field, err))
+			}
+		}
+		if fieldMapping.Description != "" {
+			if compiledField.description, err = parseMappingPath(fieldMapping.Description); err != nil {
+				problems = append(problems, fmt.Sprintf("field %q description: %s", field, err))
+			}
+		}
+		for _, transform := range fieldMapping.Transforms {
+			apply, err := parseMappingTransform(transform)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("field %q: %s", field, err))
+				continue
+			}
+			compiledField.transforms = append(compiledField.transforms, apply)
+		}
+		compiled[field] = compiledField
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid mapping %q: %s", m.Name, strings.Join(problems, "; "))
+	}
+	m.compiled = compiled
+	return nil
+}
+
+// Apply maps a decoded JSON record to a Hotel, the record is rejected when
+// the id is missing or the destination id is not a number. Coordinates that
+// are not numbers are ignored like the hand-written adapters do
+func (m *HotelMapping) Apply(record interface{}) (Hotel, error) {
+	var hotel Hotel
+	if m.compiled == nil {
+		return hotel, fmt.Errorf("mapping %q is not validated", m.Name)
+	}
+	hotel.ID = m.stringField(record, MappingFieldId)
+	if hotel.ID == "" {
+		return hotel, fmt.Errorf("record has no %s", MappingFieldId)
+	}
+	if _, present := m.Fields[MappingFieldDestinationId]; present {
+		destinationId, ok := toFloat(m.value(record, MappingFieldDestinationId))
+		if !ok {
+			return hotel, fmt.Errorf("record %s has no numeric %s", hotel.ID, MappingFieldDestinationId)
+		}
+		hotel.DestinationID = int(destinationId)
+	}
+	hotel.Name = m.stringField(record, MappingFieldName)
+	hotel.Description = m.stringField(record, MappingFieldDescription)
+	hotel.Location = HotelLocation{
+		Address: m.stringField(record, MappingFieldAddress),
+		City:    m.stringField(record, MappingFieldCity),
+		Country: m.stringField(record, MappingFieldCountry),
+	}
+	lat, latOk := toFloat(m.value(record, MappingFieldLat))
+	lng, lngOk := toFloat(m.value(record, MappingFieldLng))
+	if latOk && lngOk {
+		hotel.Location.Lat = lat
+		hotel.Location.Lng = lng
+	}
+	hotel.Amenities = HotelAmenities{
+		General: m.stringListField(record, MappingFieldGeneralAmenities),
+		Room:    m.stringListField(record, MappingFieldRoomAmenities),
+	}
+	hotel.Images = HotelImages{
+		Rooms:     m.imagesField(record, MappingFieldRoomImages),
+		Site:      m.imagesField(record, MappingFieldSiteImages),
+		Amenities: m.imagesField(record, MappingFieldAmenitiesImages),
+	}
+	hotel.BookingConditions = m.stringListField(record, MappingFieldBookingConditions)
+	return hotel, nil
+}
+
+func (m *HotelMapping) value(record interface{}, field string) interface{} {
+	return evalMappingPath(record, m.compiled[field].path)
+}
+
+// stringField returns the transformed value of a field, lists are joined with a space
+func (m *HotelMapping) stringField(record interface{}, field string) string {
+	values := applyMappingTransforms(toStrings(m.value(record, field)), m.compiled[field].transforms)
+	return strings.Join(values, " ")
+}
+
+func (m *HotelMapping) stringListField(record interface{}, field string) []string {
+	return applyMappingTransforms(toStrings(m.value(record, field)), m.compiled[field].transforms)
+}
+
+func (m *HotelMapping) imagesField(record interface{}, field string) []Image {
+	fieldMapping := m.compiled[field]
+	images := make([]Image, 0)
+	var elements []interface{}
+	switch value := m.value(record, field).(type) {
+	case []interface{}:
+		elements = value
+	case nil:
+		return images
+	default:
+		elements = []interface{}{value}
+	}
+	for _, element := range elements {
+		// plain strings are links, the transforms apply to the links so a
+		// single value can hold several links (e.g. a pipe separated column)
+		if link, ok := element.(string); ok {
+			for _, link := range applyMappingTransforms([]string{link}, fieldMapping.transforms) {
+				if link != "" {
+					images = append(images, Image{Link: link})
+				}
+			}
+			continue
+		}
+		var image Image
+		image.Link = strings.Join(toStrings(evalMappingPath(element, fieldMapping.link)), "")
+		descriptions := applyMappingTransforms(toStrings(evalMappingPath(element, fieldMapping.description)), fieldMapping.transforms)
+		image.Description = strings.Join(descriptions, " ")
+		if image.Link != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// toStrings flattens a decoded JSON value into its string values
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case []interface{}:
+		var result []string
+		for _, element := range v {
+			result = append(result, toStrings(element)...)
+		}
+		return result
+	}
+	return nil
+}
+
+// toFloat accepts JSON numbers as well as numeric strings (e.g. CSV columns)
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"datamerge/internal/utils"
+	"fmt"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const mappingPathWildcard = -1
+
+// mappingPathSegment is either an object key or an array index
+// (mappingPathWildcard selecting every element)
+type mappingPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseMappingPath parses paths such as images.rooms[*].url or items[0]
+func parseMappingPath(path string) ([]mappingPathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	var segments []mappingPathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []string
+		if bracket := strings.Index(part, "["); bracket >= 0 {
+			key = part[:bracket]
+			for _, index := range strings.Split(part[bracket+1:], "[") {
+				if !strings.HasSuffix(index, "]") {
+					return nil, fmt.Errorf("invalid path %q", path)
+				}
+				indexes = append(indexes, strings.TrimSuffix(index, "]"))
+			}
+		}
+		if key == "" && len(indexes) == 0 {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		if key != "" {
+			segments = append(segments, mappingPathSegment{key: key})
+		}
+		for _, index := range indexes {
+			if index == "*" {
+				segments = append(segments, mappingPathSegment{index: mappingPathWildcard, isIndex: true})
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index %q in path %q", index, path)
+			}
+			segments = append(segments, mappingPathSegment{index: i, isIndex: true})
+		}
+	}
+	return segments, nil
+}
+
+// evalMappingPath returns the value at the parsed path in a decoded JSON
+// value, nil when the path does not exist. Paths with a wildcard return the
+// list of every matching value
+func evalMappingPath(value interface{}, segments []mappingPathSegment) interface{} {
+	if len(segments) == 0 {
+		return nil
+	}
+	current := []interface{}{value}
+	wildcard := false
+	for _, segment := range segments {
+		var next []interface{}
+		for _, v := range current {
+			switch {
+			case !segment.isIndex:
+				object, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if child, present := object[segment.key]; present && child != nil {
+					next = append(next, child)
+				}
+			case segment.index == mappingPathWildcard:
+				// a single value is selected as is, so an XML element
+				// occurring once maps like a repeated one
+				array, ok := v.([]interface{})
+				if !ok {
+					next = append(next, v)
+					continue
+				}
+				next = append(next, array...)
+			default:
+				array, ok := v.([]interface{})
+				if !ok || segment.index >= len(array) {
+					continue
+				}
+				next = append(next, array[segment.index])
+			}
+		}
+		if segment.isIndex && segment.index == mappingPathWildcard {
+			wildcard = true
+		}
+		current = next
+	}
+	if wildcard {
+		return current
+	}
+	if len(current) == 0 {
+		return nil
+	}
+	return current[0]
+}
+
+type mappingTransform func(values []string) []string
+
+func eachValue(transform func(string) string) mappingTransform {
+	return func(values []string) []string {
+		result := make([]string, 0, len(values))
+		for _, value := range values {
+			result = append(result, transform(value))
+		}
+		return result
+	}
+}
+
+// eachValueCased applies a caser to every value, a cases.Caser is stateful
+// and suppliers are mapped concurrently so the casers are taken from a pool
+func eachValueCased(newCaser func(language.Tag, ...cases.Option) cases.Caser) mappingTransform {
+	casers := &sync.Pool{New: func() interface{} {
+		caser := newCaser(language.English)
+		return &caser
+	}}
+	return func(values []string) []string {
+		caser := casers.Get().(*cases.Caser)
+		defer casers.Put(caser)
+		return eachValue(caser.String)(values)
+	}
+}
+
+// parseMappingTransform returns the transform of a FieldMapping, split
+// takes the separator as argument, e.g. split:|
+func parseMappingTransform(transform string) (mappingTransform, error) {
+	name, argument, _ := strings.Cut(transform, ":")
+	switch name {
+	case "trim":
+		return eachValue(strings.TrimSpace), nil
+	case "lower":
+		return eachValueCased(cases.Lower), nil
+	case "upper":
+		return eachValueCased(cases.Upper), nil
+	case "title":
+		return eachValueCased(cases.Title), nil
+	case "split_camel_case":
+		return eachValue(utils.AddSpaceBetweenUpperCaseCharacters), nil
+	case "split":
+		if argument == "" {
+			return nil, fmt.Errorf("split transform requires a separator, e.g. split:|")
+		}
+		return func(values []string) []string {
+			var result []string
+			for _, value := range values {
+				for _, part := range strings.Split(value, argument) {
+					if strings.TrimSpace(part) != "" {
+						result = append(result, part)
+					}
+				}
+			}
+			return result
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", transform)
+}
+
+func applyMappingTransforms(values []string, transforms []mappingTransform) []string {
+	for _, transform := range transforms {
+		values = transform(values)
+	}
+	return values
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+var testMapping = HotelMapping{
+	Name: "supplierD",
+	Fields: map[string]FieldMapping{
+		MappingFieldId:                {Path: "code"},
+		MappingFieldDestinationId:     {Path: "destination.id"},
+		MappingFieldName:              {Path: "title", Transforms: []string{"trim"}},
+		MappingFieldLat:               {Path: "geo.lat"},
+		MappingFieldLng:               {Path: "geo.lng"},
+		MappingFieldCountry:           {Path: "geo.country", Transforms: []string{"upper"}},
+		MappingFieldGeneralAmenities:  {Path: "facilities[*]", Transforms: []string{"trim", "split_camel_case"}},
+		MappingFieldRoomAmenities:     {Path: "room", Transforms: []string{"split:|", "trim"}},
+		MappingFieldRoomImages:        {Path: "gallery[*]", Link: "src", Description: "alt"},
+		MappingFieldSiteImages:        {Path: "site_images", Transforms: []string{"split:|"}},
+		MappingFieldBookingConditions: {Path: "policies[1].text"},
+	},
+}
+
+func decodeRecord(t *testing.T, record string) interface{} {
+	var result interface{}
+	assert.Nil(t, json.Unmarshal([]byte(record), &result))
+	return result
+}
+
+func TestHotelMapping_ApplyMapsNestedFieldsAndTransforms(t *testing.T) {
+	record := decodeRecord(t, `{
+		"code": "iJhz",
+		"destination": {"id": 5432},
+		"title": "  Beach Villas  ",
+		"geo": {"lat": 1.26, "lng": "103.82", "country": "sg"},
+		"facilities": [" BusinessCenter", "Pool"],
+		"room": "tv | aircon",
+		"gallery": [{"src": "url1", "alt": "Double room"}, {"alt": "no link"}],
+		"policies": [{"text": "first"}, {"text": "second"}],
+		"site_images": "url2|url3"
+	}`)
+	assert.Nil(t, testMapping.Validate())
+	hotel, err := testMapping.Apply(record)
+	assert.Nil(t, err)
+	assert.Equal(t, hotel.ID, "iJhz")
+	assert.Equal(t, hotel.DestinationID, 5432)
+	assert.Equal(t, hotel.Name, "Beach Villas")
+	assert.Equal(t, hotel.Location, HotelLocation{Lat: 1.26, Lng: 103.82, Country: "SG"})
+	assert.Equal(t, hotel.Amenities.General, []string{"Business Center", "Pool"})
+	assert.Equal(t, hotel.Amenities.Room, []string{"tv", "aircon"})
+	assert.Equal(t, hotel.Images.Rooms, []Image{{Link: "url1", Description: "Double room"}})
+	assert.Equal(t, hotel.Images.Site, []Image{{Link: "url2"}, {Link: "url3"}})
+	assert.Equal(t, hotel.Images.Amenities, []Image{})
+	assert.Equal(t, hotel.BookingConditions, []string{"second"})
+}
+
+func TestHotelMapping_ApplyRejectsRecordsWithoutIdOrDestination(t *testing.T) {
+	assert.Nil(t, testMapping.Validate())
+	_, err := testMapping.Apply(decodeRecord(t, `{"destination": {"id": 1}}`))
+	assert.Error(t, err)
+	_, err = testMapping.Apply(decodeRecord(t, `{"code": "iJhz", "destination": {"id": "unknown"}}`))
+	assert.Error(t, err)
+}
+
+func TestHotelMapping_ValidateReportsUnknownFieldsAndTransforms(t *testing.T) {
+	mapping := HotelMapping{
+		Name: "supplierD",
+		Fields: map[string]FieldMapping{
+			"rating":               {Path: "stars"},
+			MappingFieldName:       {Path: "title", Transforms: []string{"reverse"}},
+			MappingFieldCity:       {Path: "geo..city"},
+			MappingFieldRoomImages: {Path: "gallery[x]"},
+		},
+	}
+	err := mapping.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "id field is not mapped")
+	assert.Contains(t, err.Error(), `unknown field "rating"`)
+	assert.Contains(t, err.Error(), `unknown transform "reverse"`)
+	assert.Contains(t, err.Error(), `invalid path "geo..city"`)
+	assert.Contains(t, err.Error(), `invalid index "x"`)
+}
+
+func TestHotelMapping_ApplyRequiresValidatedMapping(t *testing.T) {
+	mapping := HotelMapping{Name: "supplierD", Fields: map[string]FieldMapping{
+		MappingFieldId:         {Path: "code"},
+		MappingFieldRoomImages: {Path: "gallery[*]", Link: "src[", Description: "alt"},
+	}}
+	_, err := mapping.Apply(decodeRecord(t, `{"code": "iJhz"}`))
+	assert.EqualError(t, err, `mapping "supplierD" is not validated`)
+	err = mapping.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `field "images.rooms" link: invalid path "src["`)
+
+	mapping.Fields[MappingFieldRoomImages] = FieldMapping{Path: "gallery[*]", Link: "src", Description: "alt"}
+	assert.Nil(t, mapping.Validate())
+	hotel, err := mapping.Apply(decodeRecord(t, `{"code": "iJhz", "gallery": [{"src": "url1", "alt": "Pool"}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, hotel.Images.Rooms, []Image{{Link: "url1", Description: "Pool"}})
+}
+
+// run with -race, the transforms of a mapping are compiled once and applied
+// by suppliers mapped concurrently
+func TestHotelMapping_TransformsAreSafeForConcurrentUse(t *testing.T) {
+	transforms := make(map[string]mappingTransform)
+	for _, name := range []string{"title", "lower", "upper"} {
+		transform, err := parseMappingTransform(name)
+		assert.Nil(t, err)
+		transforms[name] = transform
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, transform := range transforms {
+				for j := 0; j < 50; j++ {
+					transform([]string{"beach VILLAS singapore"})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for name, expected := range map[string]string{"title": "Beach Villas Singapore", "lower": "beach villas singapore", "upper": "BEACH VILLAS SINGAPORE"} {
+		assert.Equal(t, transforms[name]([]string{"beach VILLAS singapore"}), []string{expected})
+	}
+}
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+const (
+	SupplierLoadStatusSuccess = "success"
+	SupplierLoadStatusFailed  = "failed"
+	// SupplierLoadStatusCached is used for suppliers that were not fetched
+	// again but merged from the data of their last successful fetch
+	SupplierLoadStatusCached = "cached"
+	// SupplierLoadStatusUnchanged is used for suppliers that answered the
+	// conditional request of the load with 304 Not Modified, their hotels
+	// are the ones of their last successful fetch
+	SupplierLoadStatusUnchanged = "unchanged"
+	// SupplierLoadStatusStale is used for suppliers that failed to load,
+	// the hotels of their last successful fetch are merged instead
+	SupplierLoadStatusStale = "stale"
+)
+
+// LoadReport summarises a single data load run over every configured supplier
+// RunID identifies the run, e.g. in the dead letters of its rejected records
+// and in the payload archive. ReplayOf is the run replayed by a replay run
+// CatalogRunID is set when the run left the catalog unchanged, it is the run
+// that built the catalog still being served
+type LoadReport struct {
+	RunID        string               `json:"run_id"`
+	ReplayOf     string               `json:"replay_of,omitempty"`
+	CatalogRunID string               `json:"catalog_run_id,omitempty"`
+	StartedAt    time.Time            `json:"started_at"`
+	FinishedAt   time.Time            `json:"finished_at"`
+	Duration     string               `json:"duration"`
+	HotelsLoaded int                  `json:"hotels_loaded"`
+	Suppliers    []SupplierLoadReport `json:"suppliers"`
+}
+
+// SupplierLoadReport describes how a single supplier was loaded
+// RecordsReceived is the number of records in the supplier payload,
+// RecordsRejected the ones that could not be converted to the supplier
+// adapter and HotelsMerged the ones merged into the catalog. Pages is only
+// set for paginated suppliers and Breaker is the state of the supplier circuit
+// breaker once the supplier was loaded
+type SupplierLoadReport struct {
+	Supplier        string               `json:"supplier"`
+	URL             string               `json:"url"`
+	Status          string               `json:"status"`
+	Error           string               `json:"error,omitempty"`
+	Err             error                `json:"-"`
+	RecordsReceived int                  `json:"records_received"`
+	RecordsRejected int                  `json:"records_rejected"`
+	HotelsMerged    int                  `json:"hotels_merged"`
+	Duration        string               `json:"duration"`
+	Pages           []SupplierPageReport `json:"pages,omitempty"`
+	Breaker         string               `json:"breaker,omitempty"`
+}
+
+// SupplierPageReport describes a single page of a paginated supplier
+type SupplierPageReport struct {
+	Page            int    `json:"page"`
+	URL             string `json:"url"`
+	RecordsReceived int    `json:"records_received"`
+	Error           string `json:"error,omitempty"`
+}
+
+// GetCatalogRunID returns the run that built the catalog served after the run
+func (r *LoadReport) GetCatalogRunID() string {
+	if r.CatalogRunID != "" {
+		return r.CatalogRunID
+	}
+	return r.RunID
+}
+
+// FailedSuppliers returns the reports of every supplier that could not be
+// loaded, including the stale ones
+func (r *LoadReport) FailedSuppliers() []SupplierLoadReport {
+	var failed []SupplierLoadReport
+	for _, supplier := range r.Suppliers {
+		if supplier.Status == SupplierLoadStatusFailed || supplier.Status == SupplierLoadStatusStale {
+			failed = append(failed, supplier)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// LoadStatus describes the outcome of the data loader runs, it is
+// exposed through the admin routes so operators can check when the
+// catalog was last refreshed and whether that refresh succeeded
+type LoadStatus struct {
+	Running        bool        `json:"running"`
+	Runs           int         `json:"runs"`
+	LastStartedAt  time.Time   `json:"last_started_at"`
+	LastFinishedAt time.Time   `json:"last_finished_at"`
+	LastDuration   string      `json:"last_duration"`
+	LastSuccess    bool        `json:"last_success"`
+	LastError      string      `json:"last_error,omitempty"`
+	NextRunAt      time.Time   `json:"next_run_at,omitempty"`
+	LastReport     *LoadReport `json:"last_report,omitempty"`
+}
+
+// Circuit breaker states of a supplier
+const (
+	BreakerStateClosed   = "closed"
+	BreakerStateOpen     = "open"
+	BreakerStateHalfOpen = "half_open"
+)
+
+// SupplierBreakerStatus describes the circuit breaker of a supplier, RetryAt
+// is when an open breaker lets the next probe fetch through
+type SupplierBreakerStatus struct {
+	Supplier            string    `json:"supplier"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at,omitempty"`
+	RetryAt             time.Time `json:"retry_at,omitempty"`
+}
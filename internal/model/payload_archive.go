@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// ArchiveManifest describes the supplier payloads archived by a load run,
+// Suppliers are in merge order so replaying the run merges them again in
+// the very same order
+type ArchiveManifest struct {
+	RunID      string             `json:"run_id"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Suppliers  []ArchivedSupplier `json:"suppliers"`
+}
+
+// ArchivedSupplier is a supplier of an archived load run. Adapter is the
+// adapter version the payloads were bound with and the feed settings tell
+// how to decode them. Merged is false for suppliers that failed in the run,
+// their payloads are kept but they are not replayed
+type ArchivedSupplier struct {
+	Supplier      string            `json:"supplier"`
+	Adapter       string            `json:"adapter"`
+	Format        string            `json:"format,omitempty"`
+	RecordsPath   string            `json:"records_path,omitempty"`
+	RecordElement string            `json:"record_element,omitempty"`
+	Status        string            `json:"status"`
+	Merged        bool              `json:"merged"`
+	Payloads      []ArchivedPayload `json:"payloads"`
+}
+
+// ArchivedPayload is a single archived payload, File is relative to the run
+// directory, Location is the (redacted) url or file it was read from and
+// Bytes and SHA256 are the size and checksum of the decompressed payload
+type ArchivedPayload struct {
+	File     string `json:"file"`
+	Location string `json:"location"`
+	Bytes    int64  `json:"bytes"`
+	SHA256   string `json:"sha256"`
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"datamerge/internal/model"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultSnapshotKeep = 3
+	snapshotPrefix      = "catalog-"
+	snapshotSuffix      = ".json.gz"
+	// snapshotTimeLayout names the snapshots after their creation time, so
+	// sorting them by name sorts them by age
+	snapshotTimeLayout = "20060102T150405.000000000Z"
+)
+
+type CatalogSnapshotStore interface {
+	ListSnapshots() ([]string, error)
+	ReadSnapshot(name string) (*model.CatalogSnapshot, error)
+	WriteSnapshot(snapshot *model.CatalogSnapshot) (string, error)
+}
+
+// FileCatalogSnapshotStore keeps the catalog snapshots as gzip compressed
+// JSON files in a directory, only the keep most recent snapshots are kept.
+// A snapshot is written to a temporary file first and renamed once complete,
+// so a crash while writing never leaves a partial snapshot behind
+type FileCatalogSnapshotStore struct {
+	dir  string
+	keep int
+}
+
+// snapshotFile is the layout of a snapshot file, the hotels are kept raw so
+// the checksum is computed over the exact bytes that were written
+type snapshotFile struct {
+	Version    int             `json:"version"`
+	CreatedAt  json.RawMessage `json:"created_at"`
+	RunID      string          `json:"run_id"`
+	HotelCount int             `json:"hotel_count"`
+	Checksum   string          `json:"checksum"`
+	Hotels     json.RawMessage `json:"hotels"`
+}
+
+func NewFileCatalogSnapshotStore(dir string, keep int) *FileCatalogSnapshotStore {
+	if keep < 1 {
+		keep = 1
+	}
+	return &FileCatalogSnapshotStore{dir: dir, keep: keep}
+}
+
+// ListSnapshots returns the names of the snapshots, most recent first
+func (f *FileCatalogSnapshotStore) ListSnapshots() ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// ReadSnapshot reads a snapshot and checks its version and checksum, a
+// SnapshotError is returned for a snapshot that cannot be used
+func (f *FileCatalogSnapshotStore) ReadSnapshot(name string) (*model.CatalogSnapshot, error) {
+	snapshot, err := f.readSnapshot(name)
+	if err != nil {
+		return nil, &model.SnapshotError{Name: name, Err: err}
+	}
+	return snapshot, nil
+}
+
+func (f *FileCatalogSnapshotStore) readSnapshot(name string) (*model.CatalogSnapshot, error) {
+	file, err := os.Open(filepath.Join(f.dir, filepath.Base(name)))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	var content snapshotFile
+	if err := json.NewDecoder(reader).Decode(&content); err != nil {
+		return nil, err
+	}
+	if content.Version != model.CatalogSnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", content.Version)
+	}
+	if checksum(content.Hotels) != content.Checksum {
+		return nil, errors.New("checksum mismatch")
+	}
+	snapshot := &model.CatalogSnapshot{
+		Version:    content.Version,
+		RunID:      content.RunID,
+		HotelCount: content.HotelCount,
+		Checksum:   content.Checksum,
+	}
+	if err := json.Unmarshal(content.CreatedAt, &snapshot.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content.Hotels, &snapshot.Hotels); err != nil {
+		return nil, err
+	}
+	if len(snapshot.Hotels) != snapshot.HotelCount {
+		return nil, fmt.Errorf("snapshot holds %d hotels instead of %d", len(snapshot.Hotels), snapshot.HotelCount)
+	}
+	return snapshot, nil
+}
+
+// WriteSnapshot writes the snapshot and deletes the snapshots beyond the keep
+// most recent ones, the version, hotel count and checksum of the snapshot are
+// set along the way. The name of the written snapshot is returned
+func (f *FileCatalogSnapshotStore) WriteSnapshot(snapshot *model.CatalogSnapshot) (string, error) {
+	hotels, err := json.Marshal(snapshot.Hotels)
+	if err != nil {
+		return "", err
+	}
+	createdAt, err := json.Marshal(snapshot.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+	snapshot.Version = model.CatalogSnapshotVersion
+	snapshot.HotelCount = len(snapshot.Hotels)
+	snapshot.Checksum = checksum(hotels)
+	content, err := json.Marshal(snapshotFile{
+		Version:    snapshot.Version,
+		CreatedAt:  createdAt,
+		RunID:      snapshot.RunID,
+		HotelCount: snapshot.HotelCount,
+		Checksum:   snapshot.Checksum,
+		Hotels:     hotels,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return "", err
+	}
+	name := snapshotPrefix + snapshot.CreatedAt.UTC().Format(snapshotTimeLayout) + snapshotSuffix
+	if err := f.writeFile(name, content); err != nil {
+		return "", err
+	}
+	return name, f.prune()
+}
+
+func (f *FileCatalogSnapshotStore) writeFile(name string, content []byte) error {
+	tmp, err := os.CreateTemp(f.dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	writer := gzip.NewWriter(tmp)
+	_, err = writer.Write(content)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(f.dir, name))
+}
+
+func (f *FileCatalogSnapshotStore) prune() error {
+	names, err := f.ListSnapshots()
+	if err != nil || len(names) <= f.keep {
+		return err
+	}
+	for _, name := range names[f.keep:] {
+		if err := os.Remove(filepath.Join(f.dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checksum(content []byte) string {
+	// the hotels are compacted when written, compact them again so an
+	// indented snapshot edited by hand is checked the same way
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, content) == nil {
+		content = compacted.Bytes()
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
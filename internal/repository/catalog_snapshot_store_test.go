@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"datamerge/internal/model"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func snapshotHotels() []*model.Hotel {
+	return []*model.Hotel{
+		{ID: "iJhz", DestinationID: 5432, Name: "Beach Villas Singapore", Description: "Sea view & pool <3"},
+		{ID: "f8c9", DestinationID: 1122, Name: "Hilton Shinjuku"},
+	}
+}
+
+func TestFileCatalogSnapshotStore_WritesAndReadsSnapshot(t *testing.T) {
+	store := NewFileCatalogSnapshotStore(filepath.Join(t.TempDir(), "snapshots"), DefaultSnapshotKeep)
+	createdAt := time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)
+	name, err := store.WriteSnapshot(&model.CatalogSnapshot{CreatedAt: createdAt, RunID: "run-1", Hotels: snapshotHotels()})
+	assert.Nil(t, err)
+	assert.Equal(t, name, "catalog-20240501T101500.000000000Z.json.gz")
+
+	snapshot, err := store.ReadSnapshot(name)
+	assert.Nil(t, err)
+	assert.Equal(t, snapshot.Version, model.CatalogSnapshotVersion)
+	assert.Equal(t, snapshot.RunID, "run-1")
+	assert.True(t, snapshot.CreatedAt.Equal(createdAt))
+	assert.Equal(t, snapshot.HotelCount, 2)
+	assert.Len(t, snapshot.Checksum, 64)
+	assert.Equal(t, snapshot.Hotels, snapshotHotels())
+}
+
+func TestFileCatalogSnapshotStore_KeepsMostRecentSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0o644))
+	store := NewFileCatalogSnapshotStore(dir, 2)
+	createdAt := time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, err := store.WriteSnapshot(&model.CatalogSnapshot{CreatedAt: createdAt.Add(time.Duration(i) * time.Millisecond), Hotels: snapshotHotels()})
+		assert.Nil(t, err)
+	}
+
+	names, err := store.ListSnapshots()
+	assert.Nil(t, err)
+	assert.Equal(t, names, []string{"catalog-20240501T101500.002000000Z.json.gz", "catalog-20240501T101500.001000000Z.json.gz"})
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.Nil(t, err)
+}
+
+func TestFileCatalogSnapshotStore_RejectsCorruptSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileCatalogSnapshotStore(dir, DefaultSnapshotKeep)
+	name, err := store.WriteSnapshot(&model.CatalogSnapshot{CreatedAt: time.Now(), Hotels: snapshotHotels()})
+	assert.Nil(t, err)
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	assert.Nil(t, err)
+
+	// a truncated snapshot is not a valid gzip stream
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), content[:len(content)/2], 0o644))
+	_, err = store.ReadSnapshot(name)
+	assert.IsType(t, err, &model.SnapshotError{})
+
+	_, err = store.ReadSnapshot("catalog-missing.json.gz")
+	assert.IsType(t, err, &model.SnapshotError{})
+}
+
+func TestFileCatalogSnapshotStore_ChecksChecksumAndVersion(t *testing.T) {
+	store := NewFileCatalogSnapshotStore(t.TempDir(), DefaultSnapshotKeep)
+	snapshot := &model.CatalogSnapshot{CreatedAt: time.Now(), Hotels: snapshotHotels()}
+	name, err := store.WriteSnapshot(snapshot)
+	assert.Nil(t, err)
+
+	// the hotels no longer match the checksum they were written with
+	assert.Nil(t, store.writeFile(name, []byte(`{"version":1,"created_at":"2024-05-01T10:15:00Z","hotel_count":0,"checksum":"`+snapshot.Checksum+`","hotels":[]}`)))
+	_, err = store.ReadSnapshot(name)
+	assert.EqualError(t, err, "catalog snapshot "+name+": checksum mismatch")
+
+	assert.Nil(t, store.writeFile(name, []byte(`{"version":2,"created_at":"2024-05-01T10:15:00Z","hotels":[]}`)))
+	_, err = store.ReadSnapshot(name)
+	assert.EqualError(t, err, "catalog snapshot "+name+": unsupported snapshot version 2")
+}
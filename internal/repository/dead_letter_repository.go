@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"datamerge/internal/model"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	DefaultDeadLetterCapacity = 1000
+	// MaxDeadLetterRecordBytes is the size a rejected record is truncated to,
+	// enough to tell why a record was rejected without keeping whole payloads
+	MaxDeadLetterRecordBytes = 4 << 10
+)
+
+type DeadLetterRepository interface {
+	AddDeadLetter(deadLetter model.DeadLetter)
+	GetDeadLetters(filter model.DeadLetterFilter) []model.DeadLetter
+	SetCapacity(capacity int)
+}
+
+// IDeadLetters exposes the supplier records rejected by the loader
+type IDeadLetters interface {
+	DeadLetters(filter model.DeadLetterFilter) []model.DeadLetter
+}
+
+// InMemoryDeadLetterRepository keeps the last capacity dead letters in a
+// ring buffer, the oldest dead letter is dropped when a new one comes in
+// once the buffer is full. A zero capacity keeps no dead letter at all
+type InMemoryDeadLetterRepository struct {
+	capacity int
+	// entries is the ring buffer, start is the index of the oldest entry
+	entries []model.DeadLetter
+	start   int
+	mu      sync.Mutex
+}
+
+func NewInMemoryDeadLetterRepository(capacity int) *InMemoryDeadLetterRepository {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &InMemoryDeadLetterRepository{capacity: capacity}
+}
+
+// AddDeadLetter stores a dead letter, the record is truncated to
+// MaxDeadLetterRecordBytes. this function is thread-safe
+func (i *InMemoryDeadLetterRepository) AddDeadLetter(deadLetter model.DeadLetter) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.capacity == 0 {
+		return
+	}
+	if record, truncated := TruncateDeadLetterRecord([]byte(deadLetter.Record)); truncated {
+		deadLetter.Record = string(record)
+		deadLetter.Truncated = true
+	}
+	if len(i.entries) < i.capacity {
+		i.entries = append(i.entries, deadLetter)
+		return
+	}
+	i.entries[i.start] = deadLetter
+	i.start = (i.start + 1) % i.capacity
+}
+
+// TruncateDeadLetterRecord truncates a rejected record to
+// MaxDeadLetterRecordBytes, without splitting a UTF-8 encoded character
+func TruncateDeadLetterRecord(record []byte) ([]byte, bool) {
+	if len(record) <= MaxDeadLetterRecordBytes {
+		return record, false
+	}
+	end := MaxDeadLetterRecordBytes
+	for end > 0 && !utf8.RuneStart(record[end]) {
+		end--
+	}
+	return record[:end], true
+}
+
+// GetDeadLetters returns the dead letters matching the filter, newest first
+// this function is thread-safe
+func (i *InMemoryDeadLetterRepository) GetDeadLetters(filter model.DeadLetterFilter) []model.DeadLetter {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	result := make([]model.DeadLetter, 0)
+	entries := i.ordered()
+	for j := len(entries) - 1; j >= 0; j-- {
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+		if filter.Matches(entries[j]) {
+			result = append(result, entries[j])
+		}
+	}
+	return result
+}
+
+// SetCapacity resizes the store, the newest dead letters are kept when it
+// shrinks. this function is thread-safe
+func (i *InMemoryDeadLetterRepository) SetCapacity(capacity int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if capacity < 0 {
+		capacity = 0
+	}
+	entries := i.ordered()
+	if len(entries) > capacity {
+		entries = entries[len(entries)-capacity:]
+	}
+	i.entries = append(make([]model.DeadLetter, 0, capacity), entries...)
+	i.start = 0
+	i.capacity = capacity
+}
+
+// ordered returns the entries oldest first, it must be called with mu held
+func (i *InMemoryDeadLetterRepository) ordered() []model.DeadLetter {
+	return append(append([]model.DeadLetter(nil), i.entries[i.start:]...), i.entries[:i.start]...)
+}
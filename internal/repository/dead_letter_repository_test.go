@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"datamerge/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func deadLetterRecords(deadLetters []model.DeadLetter) []string {
+	var records []string
+	for _, deadLetter := range deadLetters {
+		records = append(records, deadLetter.Record)
+	}
+	return records
+}
+
+func TestInMemoryDeadLetterRepository_DropsOldestDeadLetters(t *testing.T) {
+	repo := NewInMemoryDeadLetterRepository(3)
+	for _, record := range []string{"1", "2", "3", "4", "5"} {
+		repo.AddDeadLetter(model.DeadLetter{Supplier: "supplierA", Record: record})
+	}
+	assert.Equal(t, deadLetterRecords(repo.GetDeadLetters(model.DeadLetterFilter{})), []string{"5", "4", "3"})
+	assert.Equal(t, deadLetterRecords(repo.GetDeadLetters(model.DeadLetterFilter{Limit: 2})), []string{"5", "4"})
+
+	repo.SetCapacity(2)
+	repo.AddDeadLetter(model.DeadLetter{Supplier: "supplierA", Record: "6"})
+	assert.Equal(t, deadLetterRecords(repo.GetDeadLetters(model.DeadLetterFilter{})), []string{"6", "5"})
+	repo.SetCapacity(4)
+	repo.AddDeadLetter(model.DeadLetter{Supplier: "supplierA", Record: "7"})
+	assert.Equal(t, deadLetterRecords(repo.GetDeadLetters(model.DeadLetterFilter{})), []string{"7", "6", "5"})
+}
+
+func TestInMemoryDeadLetterRepository_FiltersDeadLetters(t *testing.T) {
+	repo := NewInMemoryDeadLetterRepository(DefaultDeadLetterCapacity)
+	repo.AddDeadLetter(model.DeadLetter{RunID: "run-1", Supplier: "supplierA", Record: "1"})
+	repo.AddDeadLetter(model.DeadLetter{RunID: "run-1", Supplier: "supplierB", Record: "2"})
+	repo.AddDeadLetter(model.DeadLetter{RunID: "run-2", Supplier: "supplierA", Record: "3"})
+
+	assert.Equal(t, deadLetterRecords(repo.GetDeadLetters(model.DeadLetterFilter{Supplier: "supplierA"})), []string{"3", "1"})
+	assert.Equal(t, deadLetterRecords(repo.GetDeadLetters(model.DeadLetterFilter{RunID: "run-1", Supplier: "supplierB"})), []string{"2"})
+	assert.Equal(t, repo.GetDeadLetters(model.DeadLetterFilter{RunID: "run-3"}), []model.DeadLetter{})
+}
+
+func TestInMemoryDeadLetterRepository_TruncatesLargeRecords(t *testing.T) {
+	repo := NewInMemoryDeadLetterRepository(DefaultDeadLetterCapacity)
+	repo.AddDeadLetter(model.DeadLetter{Record: strings.Repeat("x", MaxDeadLetterRecordBytes+1)})
+	deadLetters := repo.GetDeadLetters(model.DeadLetterFilter{})
+	assert.Equal(t, len(deadLetters[0].Record), MaxDeadLetterRecordBytes)
+	assert.True(t, deadLetters[0].Truncated)
+}
+
+func TestTruncateDeadLetterRecord_KeepsWholeCharacters(t *testing.T) {
+	record := []byte(strings.Repeat("x", MaxDeadLetterRecordBytes-1) + "é")
+	truncated, ok := TruncateDeadLetterRecord(record)
+	assert.True(t, ok)
+	assert.Equal(t, string(truncated), strings.Repeat("x", MaxDeadLetterRecordBytes-1))
+	truncated, ok = TruncateDeadLetterRecord([]byte("é"))
+	assert.False(t, ok)
+	assert.Equal(t, string(truncated), "é")
+}
+
+func TestInMemoryDeadLetterRepository_WithZeroCapacityKeepsNothing(t *testing.T) {
+	repo := NewInMemoryDeadLetterRepository(0)
+	repo.AddDeadLetter(model.DeadLetter{Record: "1"})
+	assert.Equal(t, len(repo.GetDeadLetters(model.DeadLetterFilter{})), 0)
+}
@@ -5,5 +5,7 @@ import "datamerge/internal/model"
 type HotelRepository interface {
 	GetHotelsByHotelIds(hotelIds []string) []*model.Hotel
 	GetHotelsByDestinationId(destinationId int) []*model.Hotel
+	GetAllHotels() []*model.Hotel
 	InsertHotel(hotel *model.Hotel)
+	ReplaceAllHotels(hotels []*model.Hotel)
 }
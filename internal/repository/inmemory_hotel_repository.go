@@ -72,3 +72,37 @@ func (i *InMemoryHotelRepository) InsertHotel(hotel *model.Hotel) {
 		i.destinationIdStore[hotel.DestinationID] = map[string]*model.Hotel{hotelIdKey: hotel}
 	}
 }
+
+// GetAllHotels returns every hotel currently stored in the repository
+// this function is thread-safe
+func (i *InMemoryHotelRepository) GetAllHotels() []*model.Hotel {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	result := make([]*model.Hotel, 0, len(i.kvStore))
+	for _, hotel := range i.kvStore {
+		result = append(result, hotel)
+	}
+	return result
+}
+
+// ReplaceAllHotels swaps the whole content of the repository with the given hotels
+// in a single step, readers will either see the previous or the new data set but
+// never a partially loaded one
+// this function is thread-safe
+func (i *InMemoryHotelRepository) ReplaceAllHotels(hotels []*model.Hotel) {
+	kvStore := make(map[string]*model.Hotel, len(hotels))
+	destinationIdStore := make(map[int]map[string]*model.Hotel, 0)
+	for _, hotel := range hotels {
+		kvStore[hotel.ID] = hotel
+		mapForDestinationId, present := destinationIdStore[hotel.DestinationID]
+		if !present {
+			mapForDestinationId = make(map[string]*model.Hotel)
+			destinationIdStore[hotel.DestinationID] = mapForDestinationId
+		}
+		mapForDestinationId[hotel.ID] = hotel
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.kvStore = kvStore
+	i.destinationIdStore = destinationIdStore
+}
@@ -119,3 +119,22 @@ func TestInMemoryHotelRepository_GetHotelsWithModification(t *testing.T) {
 	assert.Equal(t, len(hotels), 1)
 	assert.Equal(t, *hotels[0], modifiedHotelData)
 }
+
+func TestInMemoryHotelRepository_GetAllHotels(t *testing.T) {
+	repo := prefilledTestingRepository()
+	actual := repo.GetAllHotels()
+	assert.Equal(t, len(actual), 3)
+	assert.Contains(t, actual, &hotelData1)
+	assert.Contains(t, actual, &hotelData2)
+	assert.Contains(t, actual, &hotelData3)
+}
+
+func TestInMemoryHotelRepository_ReplaceAllHotels(t *testing.T) {
+	repo := prefilledTestingRepository()
+	repo.ReplaceAllHotels([]*model.Hotel{&hotelData2})
+	assert.Empty(t, repo.GetHotelsByHotelIds([]string{testHotelId1, testHotelId3}))
+	assert.Empty(t, repo.GetHotelsByDestinationId(testSingleDestinationId))
+	actual := repo.GetHotelsByDestinationId(testMultipleDestinationId)
+	assert.Equal(t, len(actual), 1)
+	assert.Equal(t, *actual[0], hotelData2)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const DefaultSnapshotInterval = 5 * time.Minute
+
+// ILastLoadReport exposes the report of the most recent load
+type ILastLoadReport interface {
+	LastReport() *model.LoadReport
+}
+
+// CatalogSnapshotService persists the serving catalog so it survives a
+// restart, the most recent snapshot is restored on startup before the
+// suppliers are loaded so the last good catalog is served even when the
+// suppliers are down. A snapshot is only written once per load run that
+// swapped the catalog, a failed load or a load leaving the catalog unchanged
+// leaves the previous snapshot in place
+type CatalogSnapshotService struct {
+	store    repository.CatalogSnapshotStore
+	repo     repository.HotelRepository
+	reports  ILastLoadReport
+	interval time.Duration
+	logger   *logrus.Logger
+	now      func() time.Time
+	mu       sync.Mutex
+	// lastRunID is the run of the catalog last snapshotted or restored
+	lastRunID string
+}
+
+func NewCatalogSnapshotService(store repository.CatalogSnapshotStore, repo repository.HotelRepository, reports ILastLoadReport, interval time.Duration, logger *logrus.Logger) *CatalogSnapshotService {
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+	return &CatalogSnapshotService{
+		store:    store,
+		repo:     repo,
+		reports:  reports,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Restore replaces the catalog with the most recent valid snapshot and
+// returns it, snapshots that cannot be read or fail their checksum are
+// logged and skipped. nil is returned when there is no valid snapshot
+func (s *CatalogSnapshotService) Restore() *model.CatalogSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, err := s.store.ListSnapshots()
+	if err != nil {
+		s.logger.WithError(err).Error("failed to list the catalog snapshots")
+		return nil
+	}
+	for _, name := range names {
+		snapshot, err := s.store.ReadSnapshot(name)
+		if err != nil {
+			s.logger.WithError(err).Warn("skipping unusable catalog snapshot")
+			continue
+		}
+		s.repo.ReplaceAllHotels(snapshot.Hotels)
+		s.lastRunID = snapshot.RunID
+		s.logger.WithFields(logrus.Fields{
+			"snapshot":   name,
+			"run_id":     snapshot.RunID,
+			"created_at": snapshot.CreatedAt,
+			"hotels":     snapshot.HotelCount,
+		}).Info("restored catalog from snapshot")
+		return snapshot
+	}
+	return nil
+}
+
+// Snapshot writes a snapshot of the catalog unless the last load failed or
+// the run that built the catalog was already snapshotted, an empty catalog is
+// never snapshotted so it can not replace the last good one
+func (s *CatalogSnapshotService) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := s.reports.LastReport()
+	if report == nil || report.HotelsLoaded == 0 || report.GetCatalogRunID() == s.lastRunID {
+		return nil
+	}
+	hotels := s.repo.GetAllHotels()
+	if len(hotels) == 0 {
+		return nil
+	}
+	snapshot := &model.CatalogSnapshot{CreatedAt: s.now(), RunID: report.GetCatalogRunID(), Hotels: hotels}
+	name, err := s.store.WriteSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	s.lastRunID = snapshot.RunID
+	s.logger.WithFields(logrus.Fields{
+		"snapshot": name,
+		"run_id":   snapshot.RunID,
+		"hotels":   snapshot.HotelCount,
+	}).Info("wrote catalog snapshot")
+	return nil
+}
+
+// Start snapshots the catalog every interval until the context is
+// cancelled, a last snapshot is taken on the way out so a catalog loaded
+// since the previous tick is not lost. it blocks until then
+func (s *CatalogSnapshotService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.snapshot()
+			return
+		case <-ticker.C:
+			s.snapshot()
+		}
+	}
+}
+
+func (s *CatalogSnapshotService) snapshot() {
+	if err := s.Snapshot(); err != nil {
+		s.logger.WithError(err).Error("failed to write catalog snapshot")
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCatalogSnapshotService_RestoresLastGoodCatalog(t *testing.T) {
+	dir := t.TempDir()
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(supplierADataset))
+	}))
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+mockHttpServer.URL), repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	snapshots := NewCatalogSnapshotService(repository.NewFileCatalogSnapshotStore(dir, repository.DefaultSnapshotKeep), repo, loader, 0, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, snapshots.Snapshot())
+	mockHttpServer.Close()
+
+	// after a restart with the supplier down the snapshot is served
+	restartedRepo := repository.NewInMemoryHotelRepository()
+	restartedLoader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+mockHttpServer.URL), restartedRepo, logger)
+	restartedLoader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	restarted := NewCatalogSnapshotService(repository.NewFileCatalogSnapshotStore(dir, repository.DefaultSnapshotKeep), restartedRepo, restartedLoader, 0, logger)
+	snapshot := restarted.Restore()
+	assert.NotNil(t, snapshot)
+	assert.Equal(t, snapshot.RunID, report.RunID)
+	assert.ElementsMatch(t, restartedRepo.GetAllHotels(), repo.GetAllHotels())
+
+	// the failed load keeps the restored catalog and writes no snapshot
+	_, err = restartedLoader.LoadData(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, len(restartedRepo.GetHotelsByHotelIds([]string{ValidHotelId})), 1)
+	assert.Nil(t, restarted.Snapshot())
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, len(entries), 1)
+}
+
+type lastReportStub struct {
+	report *model.LoadReport
+}
+
+func (l *lastReportStub) LastReport() *model.LoadReport {
+	return l.report
+}
+
+func TestCatalogSnapshotService_SnapshotsEveryLoadRunOnce(t *testing.T) {
+	store := repository.NewFileCatalogSnapshotStore(t.TempDir(), repository.DefaultSnapshotKeep)
+	repo := repository.NewInMemoryHotelRepository()
+	reports := &lastReportStub{}
+	snapshots := NewCatalogSnapshotService(store, repo, reports, 0, logger)
+	clock := time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)
+	snapshots.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	// nothing is written before the first load or for an empty catalog
+	assert.Nil(t, snapshots.Snapshot())
+	reports.report = &model.LoadReport{RunID: "run-1", HotelsLoaded: 1}
+	assert.Nil(t, snapshots.Snapshot())
+	names, _ := store.ListSnapshots()
+	assert.Equal(t, len(names), 0)
+
+	repo.ReplaceAllHotels([]*model.Hotel{{ID: ValidHotelId, Name: "Beach Villas Singapore"}})
+	assert.Nil(t, snapshots.Snapshot())
+	assert.Nil(t, snapshots.Snapshot())
+	reports.report = &model.LoadReport{RunID: "run-2"}
+	assert.Nil(t, snapshots.Snapshot())
+	names, _ = store.ListSnapshots()
+	assert.Equal(t, len(names), 1)
+
+	reports.report = &model.LoadReport{RunID: "run-3", HotelsLoaded: 1}
+	assert.Nil(t, snapshots.Snapshot())
+	names, _ = store.ListSnapshots()
+	assert.Equal(t, len(names), 2)
+}
+
+func TestCatalogSnapshotService_SkipsRunsLeavingCatalogUnchanged(t *testing.T) {
+	var feed atomic.Value
+	feed.Store(conditionalSupplierFeed)
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"%d"`, len(feed.Load().(string)))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(feed.Load().(string)))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL}}, repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	store := repository.NewFileCatalogSnapshotStore(t.TempDir(), repository.DefaultSnapshotKeep)
+	snapshots := NewCatalogSnapshotService(store, repo, loader, 0, logger)
+
+	first, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, snapshots.Snapshot())
+	second, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, second.CatalogRunID, first.RunID)
+	assert.Nil(t, snapshots.Snapshot())
+	names, _ := store.ListSnapshots()
+	assert.Equal(t, len(names), 1)
+
+	// a catalog that was not snapshotted yet is written with the run that
+	// built it, even when the last run left it unchanged
+	feed.Store(`[{"Id": "f8c9", "DestinationId": 5432, "Name": "Hilton Shinjuku"}]`)
+	third, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, third.CatalogRunID)
+	fourth, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, fourth.CatalogRunID, third.RunID)
+	assert.Nil(t, snapshots.Snapshot())
+	names, _ = store.ListSnapshots()
+	assert.Equal(t, len(names), 2)
+	snapshot, err := store.ReadSnapshot(names[0])
+	assert.Nil(t, err)
+	assert.Equal(t, snapshot.RunID, third.RunID)
+}
+
+func TestCatalogSnapshotService_RestoreSkipsCorruptSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	store := repository.NewFileCatalogSnapshotStore(dir, repository.DefaultSnapshotKeep)
+	hotels := []*model.Hotel{{ID: ValidHotelId, Name: "Beach Villas Singapore"}}
+	_, err := store.WriteSnapshot(&model.CatalogSnapshot{CreatedAt: time.Now().Add(-time.Hour), RunID: "run-1", Hotels: hotels})
+	assert.Nil(t, err)
+	newest, err := store.WriteSnapshot(&model.CatalogSnapshot{CreatedAt: time.Now(), RunID: "run-2", Hotels: hotels})
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, newest), []byte("corrupt"), 0o644))
+
+	repo := repository.NewInMemoryHotelRepository()
+	snapshot := NewCatalogSnapshotService(store, repo, &lastReportStub{}, 0, logger).Restore()
+	assert.Equal(t, snapshot.RunID, "run-1")
+	assert.Equal(t, len(repo.GetAllHotels()), 1)
+
+	empty := NewCatalogSnapshotService(repository.NewFileCatalogSnapshotStore(filepath.Join(dir, "missing"), 1), repo, &lastReportStub{}, 0, logger)
+	assert.Nil(t, empty.Restore())
+}
+
+func TestCatalogSnapshotService_SnapshotsOnShutdown(t *testing.T) {
+	store := repository.NewFileCatalogSnapshotStore(t.TempDir(), repository.DefaultSnapshotKeep)
+	repo := repository.NewInMemoryHotelRepository()
+	repo.ReplaceAllHotels([]*model.Hotel{{ID: ValidHotelId}})
+	snapshots := NewCatalogSnapshotService(store, repo, &lastReportStub{&model.LoadReport{RunID: "run-1", HotelsLoaded: 1}}, time.Hour, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	snapshots.Start(ctx)
+	names, _ := store.ListSnapshots()
+	assert.Equal(t, len(names), 1)
+}
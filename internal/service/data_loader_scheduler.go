@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/model"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type IDataLoaderScheduler interface {
+	Status() model.LoadStatus
+}
+
+// DataLoaderScheduler periodically re-runs a DataLoaderService so supplier
+// changes are picked up without restarting the application.
+// Every refresh waits for interval plus a random duration in [0, jitter)
+// so multiple replicas do not hit the suppliers at the same time.
+// Runs never overlap, and since the loader only swaps the catalog once a run
+// completes the previous catalog keeps being served during a refresh
+type DataLoaderScheduler struct {
+	loader   DataLoaderService
+	interval time.Duration
+	jitter   time.Duration
+	logger   *logrus.Logger
+	random   *rand.Rand
+	runMu    sync.Mutex
+	mu       sync.RWMutex
+	status   model.LoadStatus
+}
+
+func NewDataLoaderScheduler(loader DataLoaderService, interval, jitter time.Duration, logger *logrus.Logger) *DataLoaderScheduler {
+	return &DataLoaderScheduler{
+		loader:   loader,
+		interval: interval,
+		jitter:   jitter,
+		logger:   logger,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// RunOnce triggers a single load and records its outcome
+// concurrent calls are serialized so only one load runs at a time,
+// cancelling the context aborts the in-flight supplier fetches
+func (s *DataLoaderScheduler) RunOnce(ctx context.Context) error {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	startedAt := time.Now()
+	s.mu.Lock()
+	s.status.Running = true
+	s.status.LastStartedAt = startedAt
+	s.mu.Unlock()
+
+	report, err := s.loader.LoadData(ctx)
+
+	finishedAt := time.Now()
+	s.mu.Lock()
+	s.status.Running = false
+	s.status.Runs++
+	s.status.LastFinishedAt = finishedAt
+	s.status.LastDuration = finishedAt.Sub(startedAt).String()
+	s.status.LastSuccess = err == nil
+	s.status.LastError = ""
+	s.status.LastReport = report
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	fields := logrus.Fields{"duration": finishedAt.Sub(startedAt).String()}
+	if err != nil {
+		s.logger.WithFields(fields).Warn("data load failed, keeping previous catalog: ", err)
+	} else {
+		s.logger.WithFields(fields).Debug("data load finished")
+	}
+	return err
+}
+
+// Start blocks and refreshes the data every interval (plus jitter) until
+// the context is cancelled. A non-positive interval disables the refresh
+func (s *DataLoaderScheduler) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	for {
+		delay := s.nextDelay()
+		s.mu.Lock()
+		s.status.NextRunAt = time.Now().Add(delay)
+		s.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.RunOnce(ctx)
+		}
+	}
+}
+
+// Status returns a copy of the last recorded load outcome
+func (s *DataLoaderScheduler) Status() model.LoadStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *DataLoaderScheduler) nextDelay() time.Duration {
+	if s.jitter <= 0 {
+		return s.interval
+	}
+	return s.interval + time.Duration(s.random.Int63n(int64(s.jitter)))
+}
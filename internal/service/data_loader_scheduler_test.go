@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/model"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingDataLoader struct {
+	calls int32
+	err   error
+}
+
+func (c *countingDataLoader) LoadData(ctx context.Context) (*model.LoadReport, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return &model.LoadReport{HotelsLoaded: 1}, c.err
+}
+
+func TestDataLoaderScheduler_RunOnceRecordsSuccess(t *testing.T) {
+	loader := &countingDataLoader{}
+	scheduler := NewDataLoaderScheduler(loader, 0, 0, logger)
+	err := scheduler.RunOnce(context.Background())
+	assert.Nil(t, err)
+	status := scheduler.Status()
+	assert.Equal(t, status.Runs, 1)
+	assert.True(t, status.LastSuccess)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, status.LastReport.HotelsLoaded, 1)
+	assert.False(t, status.LastFinishedAt.Before(status.LastStartedAt))
+}
+
+func TestDataLoaderScheduler_RunOnceRecordsFailure(t *testing.T) {
+	loader := &countingDataLoader{err: errors.New("supplier down")}
+	scheduler := NewDataLoaderScheduler(loader, 0, 0, logger)
+	err := scheduler.RunOnce(context.Background())
+	assert.Error(t, err)
+	status := scheduler.Status()
+	assert.False(t, status.LastSuccess)
+	assert.Equal(t, status.LastError, "supplier down")
+}
+
+func TestDataLoaderScheduler_StartRefreshesUntilCancelled(t *testing.T) {
+	loader := &countingDataLoader{}
+	scheduler := NewDataLoaderScheduler(loader, 5*time.Millisecond, time.Millisecond, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Start(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&loader.calls) >= 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.GreaterOrEqual(t, scheduler.Status().Runs, 2)
+}
+
+func TestDataLoaderScheduler_StartWithoutIntervalReturnsImmediately(t *testing.T) {
+	loader := &countingDataLoader{}
+	scheduler := NewDataLoaderScheduler(loader, 0, 0, logger)
+	scheduler.Start(context.Background())
+	assert.Equal(t, atomic.LoadInt32(&loader.calls), int32(0))
+}
@@ -1,5 +1,10 @@
 package service
 
+import (
+	"context"
+	"datamerge/internal/model"
+)
+
 type DataLoaderService interface {
-	LoadData() error
+	LoadData(ctx context.Context) (*model.LoadReport, error)
 }
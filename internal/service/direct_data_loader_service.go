@@ -1,88 +1,956 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"datamerge/internal/config"
 	"datamerge/internal/model"
 	"datamerge/internal/repository"
-	"encoding/json"
+	"datamerge/internal/utils"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	ConfigSubstringLimitSeparator = 2
+	DefaultSupplierConcurrency = 4
 )
 
-// DirectDataLoaderService will load json data from the configUrls directly
-// the object type assigned from each url are given through the configs and
-// returned from the CreateSupplier factory method
-// Raw JSON returned from the url is converted to the supplier object type
-// using the ConvertToHotelLoaderData defined by each supplier class
+// DirectDataLoaderService will load json data from the supplier urls directly,
+// either http(s) urls or local file:// files, directories and glob patterns
+// the object type assigned from each url are given through the supplier adapter
+// configs and looked up from the adapter registry
+// Raw JSON returned from the url is streamed record by record and bound to
+// the supplier object type using model.DecodeHotelLoaderData
 // newHotelData will then be merged with existing data by querying them
 // from the repository using their hotelId (acts as the PK in this case)
+// Every load is merged into a staging repository first and only swapped into
+// the serving repository once all suppliers are processed, so readers keep
+// getting the previous catalog while a load is running
 type DirectDataLoaderService struct {
-	configs                string
-	repo                   repository.HotelRepository
-	hotelLoaderDataFactory model.HotelLoaderDataFactory
-	logger                 *logrus.Logger
+	suppliers    []config.SupplierConfig
+	repo         repository.HotelRepository
+	adapters     model.IHotelLoaderDataRegistry
+	logger       *logrus.Logger
+	clientConfig SupplierHttpClientConfig
+	concurrency  int
+	mu           sync.RWMutex
+	lastReport   *model.LoadReport
+	// breakers and limiters are kept per supplier name across loads
+	breakerConfig SupplierBreakerConfig
+	breakers      map[string]*supplierBreaker
+	rateLimit     SupplierRateLimit
+	limiters      map[string]*rateLimiter
+	limits        SupplierPayloadLimits
+	deadLetters   repository.DeadLetterRepository
+	archive       *payloadArchive
+	// loadMu serializes loads, lastResults holds the result of the last
+	// successful fetch of every supplier, mergedSuppliers the suppliers
+	// merged into the serving catalog, in merge order, and catalogRunID the
+	// load run that merged them. They are only accessed under loadMu. lastResults keeps the bound hotels of every
+	// supplier for the life of the process, next to the serving catalog
+	loadMu          sync.Mutex
+	lastResults     map[string]supplierFetchResult
+	mergedSuppliers []string
+	catalogRunID    string
+	catalogRestored bool
 }
 
-func NewDirectDataLoaderService(configs string, repo repository.HotelRepository, logger *logrus.Logger) *DirectDataLoaderService {
-	return &DirectDataLoaderService{configs: configs, repo: repo, logger: logger}
+func NewDirectDataLoaderService(suppliers []config.SupplierConfig, repo repository.HotelRepository, logger *logrus.Logger) *DirectDataLoaderService {
+	return &DirectDataLoaderService{
+		suppliers:    suppliers,
+		repo:         repo,
+		adapters:     model.DefaultHotelLoaderDataRegistry,
+		logger:       logger,
+		clientConfig: DefaultSupplierHttpClientConfig(),
+		concurrency:  DefaultSupplierConcurrency,
+		lastResults:  make(map[string]supplierFetchResult),
+
+		breakerConfig: DefaultSupplierBreakerConfig(),
+		breakers:      make(map[string]*supplierBreaker),
+		limiters:      make(map[string]*rateLimiter),
+		limits:        DefaultSupplierPayloadLimits(),
+		deadLetters:   repository.NewInMemoryDeadLetterRepository(repository.DefaultDeadLetterCapacity),
+	}
+}
+
+// SetAdapterRegistry sets the registry the supplier adapters are looked up from
+func (d *DirectDataLoaderService) SetAdapterRegistry(adapters model.IHotelLoaderDataRegistry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.adapters = adapters
+}
+
+// ValidateSuppliers checks that the adapter of every supplier is registered
+func (d *DirectDataLoaderService) ValidateSuppliers(suppliers []config.SupplierConfig) error {
+	d.mu.RLock()
+	adapters := d.adapters
+	d.mu.RUnlock()
+	return ValidateSupplierAdapters(adapters, suppliers)
+}
+
+// ValidateSupplierAdapters checks that the adapter of every supplier is
+// registered in adapters
+func ValidateSupplierAdapters(adapters model.IHotelLoaderDataRegistry, suppliers []config.SupplierConfig) error {
+	var problems []string
+	for _, supplier := range suppliers {
+		_, err := adapters.Lookup(supplier.GetAdapter())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("supplier %q: %s", supplier.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return &config.SupplierConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// SetConcurrency sets how many suppliers are fetched at the same time
+func (d *DirectDataLoaderService) SetConcurrency(concurrency int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.concurrency = concurrency
+}
+
+// SetHttpClientConfig sets the default http client configuration, the timeout
+// can be overridden per supplier through the supplier config
+func (d *DirectDataLoaderService) SetHttpClientConfig(config SupplierHttpClientConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clientConfig = config
+}
+
+// SetBreakerConfig sets the circuit breaker configuration of every supplier,
+// the state of the existing breakers is kept
+func (d *DirectDataLoaderService) SetBreakerConfig(config SupplierBreakerConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakerConfig = config
+	for _, breaker := range d.breakers {
+		breaker.setConfig(config)
+	}
+}
+
+// SetRateLimit sets the default rate limit of the supplier requests, it can be
+// overridden per supplier through the supplier config
+func (d *DirectDataLoaderService) SetRateLimit(limit SupplierRateLimit) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rateLimit = limit
+}
+
+// SetPayloadLimits sets the default payload limits of the suppliers, they can
+// be overridden per supplier through the supplier config
+func (d *DirectDataLoaderService) SetPayloadLimits(limits SupplierPayloadLimits) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.limits = limits
+}
+
+// SetDeadLetterCapacity sets how many rejected records are kept, the oldest
+// ones are dropped first
+func (d *DirectDataLoaderService) SetDeadLetterCapacity(capacity int) {
+	d.deadLetters.SetCapacity(capacity)
 }
 
-func readJsonFileFromUrl(url string) ([]interface{}, error) {
-	resp, err := http.Get(url)
+// DeadLetters returns the rejected records matching the filter, newest first
+func (d *DirectDataLoaderService) DeadLetters(filter model.DeadLetterFilter) []model.DeadLetter {
+	return d.deadLetters.GetDeadLetters(filter)
+}
+
+// SetPayloadArchive sets the payload archive configuration, an empty
+// directory disables the archive. When enabled every payload is archived as
+// it is decoded, so a past load run can be replayed with ReplayRun
+func (d *DirectDataLoaderService) SetPayloadArchive(config PayloadArchiveConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.archive = newPayloadArchive(config)
+}
+
+// MarkCatalogRestored tells the loader the serving catalog was restored from
+// a snapshot. Until a load swaps the catalog, a load leaving out a supplier
+// that never loaded successfully keeps the restored catalog instead
+func (d *DirectDataLoaderService) MarkCatalogRestored() {
+	d.loadMu.Lock()
+	defer d.loadMu.Unlock()
+	d.catalogRestored = true
+}
+
+// SetSuppliers replaces the supplier list used by the following loads
+func (d *DirectDataLoaderService) SetSuppliers(suppliers []config.SupplierConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.suppliers = suppliers
+}
+
+// clientFor returns the http client of the supplier, the client has to be
+// closed once the supplier is fetched
+func (d *DirectDataLoaderService) clientFor(supplier config.SupplierConfig) (*SupplierHttpClient, error) {
+	d.mu.RLock()
+	clientConfig := d.clientConfig
+	d.mu.RUnlock()
+	if supplier.Timeout > 0 {
+		clientConfig.Timeout = supplier.Timeout
+	}
+	clientConfig.Auth = supplier.Auth
+	if supplier.TLS != nil {
+		tlsConfig, err := newSupplierTLSConfig(supplier.TLS)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.TLS = tlsConfig
+	}
+	client := NewSupplierHttpClient(clientConfig)
+	client.limiter = d.limiterFor(supplier)
+	return client, nil
+}
+
+// limiterFor returns the rate limiter of the supplier, a new limiter replaces
+// the previous one when the rate limit of the supplier changed. The limiter is
+// applied to every request sent to the supplier
+func (d *DirectDataLoaderService) limiterFor(supplier config.SupplierConfig) *rateLimiter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	limit := d.rateLimit
+	if supplier.RateLimit > 0 {
+		limit = SupplierRateLimit{Rate: supplier.RateLimit, Burst: supplier.RateBurst}
+	}
+	limiter, present := d.limiters[supplier.Name]
+	if !present || limiter.limit != limit {
+		limiter = newRateLimiter(limit)
+		d.limiters[supplier.Name] = limiter
+	}
+	return limiter
+}
+
+// breakerFor returns the circuit breaker of the supplier, breakers are kept
+// across loads so a failing supplier is not fetched again on every load
+func (d *DirectDataLoaderService) breakerFor(name string) *supplierBreaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	breaker, present := d.breakers[name]
+	if !present {
+		breaker = newSupplierBreaker(d.breakerConfig)
+		d.breakers[name] = breaker
+	}
+	return breaker
+}
+
+// Breakers returns the circuit breaker status of every enabled supplier in
+// merge order
+func (d *DirectDataLoaderService) Breakers() []model.SupplierBreakerStatus {
+	d.mu.RLock()
+	suppliers := orderedSuppliers(d.suppliers)
+	d.mu.RUnlock()
+	statuses := make([]model.SupplierBreakerStatus, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		statuses = append(statuses, d.breakerFor(supplier.Name).status(supplier.Name))
+	}
+	return statuses
+}
+
+// LoadData fetches and merges every configured supplier. Suppliers are fetched
+// concurrently (bounded by the configured concurrency) but merged one after the
+// other in configuration order, so the merged catalog does not depend on which
+// supplier answered first. A failing supplier does not stop the load, it is
+// recorded in the returned report and the remaining suppliers are still merged,
+// along with the hotels of its last successful fetch reported as stale.
+// An error is only returned when no supplier could be loaded, in which case the
+// catalog is left untouched. Every load gets a run ID, the records rejected
+// during the load are kept in the dead letters along with it
+func (d *DirectDataLoaderService) LoadData(ctx context.Context) (*model.LoadReport, error) {
+	return d.load(ctx, nil)
+}
+
+// ReloadSuppliers fetches only the given suppliers again, every other supplier
+// is merged from the data of its last successful fetch. It is used to apply
+// supplier configuration changes without fetching every supplier
+func (d *DirectDataLoaderService) ReloadSuppliers(ctx context.Context, names []string) (*model.LoadReport, error) {
+	reload := make(map[string]bool, len(names))
+	for _, name := range names {
+		reload[name] = true
+	}
+	return d.load(ctx, reload)
+}
+
+// load fetches the suppliers in reload (every supplier when reload is nil,
+// or when a supplier was never fetched successfully) and merges them with
+// the cached results of the others
+func (d *DirectDataLoaderService) load(ctx context.Context, reload map[string]bool) (*model.LoadReport, error) {
+	d.loadMu.Lock()
+	defer d.loadMu.Unlock()
+
+	startedAt := time.Now()
+	report := &model.LoadReport{RunID: newLoadRunID(startedAt), StartedAt: startedAt}
+	d.mu.RLock()
+	suppliers := orderedSuppliers(d.suppliers)
+	d.mu.RUnlock()
+
+	var toFetch []config.SupplierConfig
+	for _, supplier := range suppliers {
+		_, cached := d.lastResults[supplier.Name]
+		if reload == nil || reload[supplier.Name] || !cached {
+			toFetch = append(toFetch, supplier)
+		}
+	}
+	fetched := make(map[string]supplierFetchResult, len(toFetch))
+	for _, result := range d.fetchSuppliers(ctx, report.RunID, toFetch, d.lastResults) {
+		fetched[result.report.Supplier] = result
+	}
+
+	staging := repository.NewInMemoryHotelRepository()
+	results := make(map[string]supplierFetchResult, len(suppliers))
+	var errs, missingErrs []error
+	var merged, missing []string
+	for _, supplier := range suppliers {
+		result, present := fetched[supplier.Name]
+		if !present {
+			result = d.lastResults[supplier.Name]
+			result.report.Status = model.SupplierLoadStatusCached
+			result.report.Duration = "0s"
+		}
+		if result.report.Err != nil {
+			errs = append(errs, result.report.Err)
+			last, cached := d.lastResults[supplier.Name]
+			if !cached {
+				missing = append(missing, supplier.Name)
+				missingErrs = append(missingErrs, result.report.Err)
+				continue
+			}
+			result = staleResult(last, result.report)
+		}
+		results[supplier.Name] = result
+		merged = append(merged, supplier.Name)
+	}
+
+	var err error
+	if len(errs) == len(suppliers) {
+		err = &model.LoadError{Errs: errs}
+	} else if d.catalogRestored && len(missing) > 0 {
+		// the restored catalog holds hotels of suppliers that have no data
+		// to fall back on yet, merging the others would drop them
+		err = &model.IncompleteCatalogError{Suppliers: missing, Errs: missingErrs}
+	} else if d.catalogUnchanged(results, merged) {
+		// the serving catalog was merged from the very same data
+		report.HotelsLoaded = len(d.repo.GetAllHotels())
+		report.CatalogRunID = d.catalogRunID
+	} else {
+		for _, name := range merged {
+			result := results[name]
+			result.report.HotelsMerged = mergeSupplierData(staging, result.hotels)
+			results[name] = result
+		}
+		hotels := staging.GetAllHotels()
+		d.repo.ReplaceAllHotels(hotels)
+		report.HotelsLoaded = len(hotels)
+		d.mergedSuppliers = merged
+		d.catalogRunID = report.RunID
+		d.catalogRestored = false
+		// suppliers that failed keep their previous results for the next
+		// partial reload, suppliers no longer configured are dropped
+		lastResults := make(map[string]supplierFetchResult, len(d.lastResults))
+		for name, result := range results {
+			if result.report.Status != model.SupplierLoadStatusStale {
+				lastResults[name] = result
+			}
+		}
+		for name, result := range d.lastResults {
+			if _, present := lastResults[name]; !present && containsSupplier(suppliers, name) {
+				lastResults[name] = result
+			}
+		}
+		d.lastResults = lastResults
+	}
+	for _, supplier := range suppliers {
+		result, present := results[supplier.Name]
+		if !present {
+			result = fetched[supplier.Name]
+		}
+		d.logSupplierReport(report.RunID, result.report)
+		report.Suppliers = append(report.Suppliers, result.report)
+	}
+	report.FinishedAt = time.Now()
+	report.Duration = report.FinishedAt.Sub(report.StartedAt).String()
+
+	d.mu.RLock()
+	archive := d.archive
+	d.mu.RUnlock()
+	if archive != nil {
+		d.archiveRun(archive, report, suppliers, results, fetched)
+	}
+
+	d.mu.Lock()
+	d.lastReport = report
+	d.mu.Unlock()
+	return report, err
+}
+
+// newLoadRunID returns the ID of a load run, the start time of the run makes
+// the IDs sortable and the random suffix tells apart runs started together
+func newLoadRunID(startedAt time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return startedAt.UTC().Format(runIDTimeLayout) + "-" + hex.EncodeToString(suffix)
+}
+
+// archiveRun writes the manifest of the run once every supplier is loaded,
+// the payloads of the merged suppliers that were not fetched in the run
+// (unchanged, cached or stale suppliers) are linked from the run they were
+// fetched in, the payloads of a failed fetch replaced by a stale result are
+// removed first. The runs past the retention of the archive are deleted then
+func (d *DirectDataLoaderService) archiveRun(archive *payloadArchive, report *model.LoadReport, suppliers []config.SupplierConfig, results, fetched map[string]supplierFetchResult) {
+	manifest := &model.ArchiveManifest{
+		RunID:      report.RunID,
+		StartedAt:  report.StartedAt,
+		FinishedAt: report.FinishedAt,
+	}
+	for _, supplier := range suppliers {
+		result, merged := results[supplier.Name]
+		if !merged {
+			result = fetched[supplier.Name]
+		}
+		if result.report.Status == model.SupplierLoadStatusStale {
+			if err := archive.removePayloads(report.RunID, fetched[supplier.Name].payloads); err != nil {
+				d.logArchiveError(report.RunID, supplier.Name, err)
+			}
+		}
+		payloads := result.payloads
+		if result.archivedIn != "" && result.archivedIn != report.RunID {
+			if err := archive.linkPayloads(result.archivedIn, report.RunID, payloads); err != nil {
+				d.logArchiveError(report.RunID, supplier.Name, err)
+				payloads = nil
+			} else if last, present := d.lastResults[supplier.Name]; present && last.archivedIn == result.archivedIn {
+				// the next runs link the payloads from the most recent run
+				last.archivedIn = report.RunID
+				d.lastResults[supplier.Name] = last
+			}
+		}
+		manifest.Suppliers = append(manifest.Suppliers, model.ArchivedSupplier{
+			Supplier:      supplier.Name,
+			Adapter:       result.adapterName,
+			Format:        result.supplier.Format,
+			RecordsPath:   result.supplier.RecordsPath,
+			RecordElement: result.supplier.RecordElement,
+			Status:        result.report.Status,
+			Merged:        merged,
+			Payloads:      payloads,
+		})
+	}
+	fields := logrus.Fields{"run_id": report.RunID}
+	if err := archive.writeManifest(manifest); err != nil {
+		d.logger.WithFields(fields).Warn("unable to write archive manifest: ", err)
+	}
+	deleted, err := archive.prune(report.RunID)
 	if err != nil {
-		return nil, &model.HttpError{}
+		d.logger.WithFields(fields).Warn("unable to prune payload archive: ", err)
+	}
+	if len(deleted) > 0 {
+		d.logger.WithFields(fields).WithField("deleted_runs", deleted).Info("payload archive pruned")
+	}
+}
+
+func (d *DirectDataLoaderService) logArchiveError(runID, supplier string, err error) {
+	d.logger.WithFields(logrus.Fields{
+		"run_id":   runID,
+		"supplier": supplier,
+	}).Warn("unable to archive supplier payload: ", err)
+}
+
+// ArchivedRuns returns the manifest of every archived load run, most recent
+// first
+func (d *DirectDataLoaderService) ArchivedRuns() ([]model.ArchiveManifest, error) {
+	d.mu.RLock()
+	archive := d.archive
+	d.mu.RUnlock()
+	if archive == nil {
+		return nil, model.ErrArchiveDisabled
+	}
+	return archive.manifests()
+}
+
+// ReplayRun merges the payloads archived by a past load run again and
+// replaces the catalog with the result. The suppliers merged in the run are
+// bound with the adapter versions of the run and merged in the same order,
+// unless currentRules is set: the adapters and the merge order of the current
+// supplier configuration are used then and the suppliers no longer configured
+// are left out. Every payload is checked against its checksum first, and like
+// a load the catalog is left untouched when no supplier could be replayed
+func (d *DirectDataLoaderService) ReplayRun(ctx context.Context, runID string, currentRules bool) (*model.LoadReport, error) {
+	d.mu.RLock()
+	archive := d.archive
+	current := orderedSuppliers(d.suppliers)
+	d.mu.RUnlock()
+	if archive == nil {
+		return nil, model.ErrArchiveDisabled
 	}
-	var result []interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
+	manifest, err := archive.readManifest(runID)
 	if err != nil {
-		return nil, &model.JsonError{}
+		return nil, err
+	}
+
+	d.loadMu.Lock()
+	defer d.loadMu.Unlock()
+	startedAt := time.Now()
+	report := &model.LoadReport{RunID: newLoadRunID(startedAt), ReplayOf: manifest.RunID, StartedAt: startedAt}
+	staging := repository.NewInMemoryHotelRepository()
+	var errs []error
+	replayed := replayedSuppliers(manifest, current, currentRules)
+	for _, archived := range replayed {
+		result := d.replaySupplier(ctx, report.RunID, archive, manifest.RunID, archived)
+		if result.report.Err != nil {
+			errs = append(errs, result.report.Err)
+		} else {
+			result.report.HotelsMerged = mergeSupplierData(staging, result.hotels)
+		}
+		d.logSupplierReport(report.RunID, result.report)
+		report.Suppliers = append(report.Suppliers, result.report)
+	}
+	if len(errs) == len(replayed) {
+		err = &model.LoadError{Errs: errs}
+	} else {
+		hotels := staging.GetAllHotels()
+		d.repo.ReplaceAllHotels(hotels)
+		report.HotelsLoaded = len(hotels)
+		// the serving catalog no longer matches the last fetched suppliers,
+		// the next load merges them again even when they are unchanged
+		d.mergedSuppliers = nil
+	}
+	report.FinishedAt = time.Now()
+	report.Duration = report.FinishedAt.Sub(report.StartedAt).String()
+
+	d.mu.Lock()
+	d.lastReport = report
+	d.mu.Unlock()
+	return report, err
+}
+
+// replayedSuppliers returns the suppliers of the archived run to replay in
+// merge order, see ReplayRun
+func replayedSuppliers(manifest *model.ArchiveManifest, current []config.SupplierConfig, currentRules bool) []model.ArchivedSupplier {
+	var replayed []model.ArchivedSupplier
+	archived := make(map[string]model.ArchivedSupplier, len(manifest.Suppliers))
+	for _, supplier := range manifest.Suppliers {
+		if !supplier.Merged {
+			continue
+		}
+		if !currentRules {
+			replayed = append(replayed, supplier)
+		}
+		archived[supplier.Supplier] = supplier
+	}
+	if !currentRules {
+		return replayed
+	}
+	for _, supplier := range current {
+		if replay, present := archived[supplier.Name]; present {
+			replay.Adapter = supplier.GetAdapter()
+			replayed = append(replayed, replay)
+		}
+	}
+	return replayed
+}
+
+// replaySupplier decodes the payloads archived for the supplier in the run
+func (d *DirectDataLoaderService) replaySupplier(ctx context.Context, runID string, archive *payloadArchive, archivedRun string, archived model.ArchivedSupplier) supplierFetchResult {
+	startedAt := time.Now()
+	supplier := config.SupplierConfig{
+		Name:          archived.Supplier,
+		Adapter:       archived.Adapter,
+		URL:           "file://" + filepath.ToSlash(filepath.Join(archive.runDir(archivedRun), archiveDirName(archived.Supplier))),
+		Format:        archived.Format,
+		RecordsPath:   archived.RecordsPath,
+		RecordElement: archived.RecordElement,
+	}
+	result := newSupplierFetchResult(supplier)
+	d.mu.RLock()
+	adapters := d.adapters
+	d.mu.RUnlock()
+	supplierModel, err := adapters.Lookup(supplier.GetAdapter())
+	if err != nil {
+		return result.fail(err, startedAt)
+	}
+	result.adapter = supplierModel
+	onPayload := d.supplierPayloadHandler(runID, supplier, &result, nil)
+	for _, payload := range archived.Payloads {
+		if err := ctx.Err(); err != nil {
+			return result.fail(err, startedAt)
+		}
+		if err := archive.verifyPayload(archivedRun, payload); err != nil {
+			return result.fail(err, startedAt)
+		}
+		err := readFilePayload(filepath.Join(archive.runDir(archivedRun), filepath.FromSlash(payload.File)), onPayload)
+		if err != nil {
+			return result.fail(err, startedAt)
+		}
+	}
+	result.report.Duration = time.Since(startedAt).String()
+	return result
+}
+
+// LastReport returns the report of the most recent load, nil if no load ran yet
+func (d *DirectDataLoaderService) LastReport() *model.LoadReport {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.lastReport
+}
+
+// catalogUnchanged reports whether every merged supplier is unchanged or
+// cached and the serving catalog was merged from the same suppliers in the
+// same order, the merge and swap can be skipped altogether then
+func (d *DirectDataLoaderService) catalogUnchanged(results map[string]supplierFetchResult, merged []string) bool {
+	if len(merged) == 0 || len(merged) != len(d.mergedSuppliers) {
+		return false
+	}
+	for i, name := range merged {
+		status := results[name].report.Status
+		if name != d.mergedSuppliers[i] || (status != model.SupplierLoadStatusUnchanged &&
+			status != model.SupplierLoadStatusCached && status != model.SupplierLoadStatusStale) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsSupplier(suppliers []config.SupplierConfig, name string) bool {
+	for _, supplier := range suppliers {
+		if supplier.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// supplierFetchResult holds the converted hotels of a supplier until it is
+// its turn to be merged, along with the supplier config, the adapter and the
+// payload validators they were fetched with. adapterName is the adapter
+// version and payloads the payloads archived in the run archivedIn
+type supplierFetchResult struct {
+	report      model.SupplierLoadReport
+	hotels      []model.HotelLoaderData
+	supplier    config.SupplierConfig
+	adapter     model.HotelLoaderData
+	adapterName string
+	validators  cacheValidators
+	payloads    []model.ArchivedPayload
+	archivedIn  string
+}
+
+// fail marks the supplier as failed with the given error
+func (r supplierFetchResult) fail(err error, startedAt time.Time) supplierFetchResult {
+	r.report.Status = model.SupplierLoadStatusFailed
+	r.report.Err = err
+	r.report.Error = err.Error()
+	r.report.Duration = time.Since(startedAt).String()
+	return r
+}
+
+// staleResult returns the last successful result of a supplier that failed
+// to load, reported as stale with the error of the failed fetch
+func staleResult(last supplierFetchResult, failed model.SupplierLoadReport) supplierFetchResult {
+	failed.Status = model.SupplierLoadStatusStale
+	last.report = failed
+	return last
+}
+
+// orderedSuppliers returns the enabled suppliers in merge order, that is
+// ascending priority with ties kept in declaration order
+func orderedSuppliers(suppliers []config.SupplierConfig) []config.SupplierConfig {
+	var enabled []config.SupplierConfig
+	for _, supplier := range suppliers {
+		if supplier.IsEnabled() {
+			enabled = append(enabled, supplier)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return enabled[i].Priority < enabled[j].Priority
+	})
+	return enabled
+}
+
+// fetchSuppliers fetches every supplier with at most d.concurrency suppliers in
+// flight, the results are returned in the same order as the suppliers
+// previous holds the last results the conditional requests are made from
+func (d *DirectDataLoaderService) fetchSuppliers(ctx context.Context, runID string, suppliers []config.SupplierConfig, previous map[string]supplierFetchResult) []supplierFetchResult {
+	results := make([]supplierFetchResult, len(suppliers))
+	d.mu.RLock()
+	concurrency := d.concurrency
+	d.mu.RUnlock()
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, supplier := range suppliers {
+		wg.Add(1)
+		var previousResult *supplierFetchResult
+		if result, present := previous[supplier.Name]; present {
+			previousResult = &result
+		}
+		go func(i int, supplier config.SupplierConfig, previous *supplierFetchResult) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = d.fetchSupplier(ctx, runID, supplier, previous)
+		}(i, supplier, previousResult)
+	}
+	wg.Wait()
+	return results
+}
+
+// fetchSupplier fetches a single supplier unless its circuit breaker is open,
+// the outcome of the fetch is recorded by the breaker. A fetch aborted by the
+// cancellation of the load is not held against the supplier
+func (d *DirectDataLoaderService) fetchSupplier(ctx context.Context, runID string, supplier config.SupplierConfig, previous *supplierFetchResult) supplierFetchResult {
+	breaker := d.breakerFor(supplier.Name)
+	probe, err := breaker.allow()
+	if err != nil {
+		result := newSupplierFetchResult(supplier).fail(err, time.Now())
+		result.report.Breaker = model.BreakerStateOpen
+		return result
+	}
+	fields := logrus.Fields{"supplier": supplier.Name}
+	if probe {
+		d.logger.WithFields(fields).Info("supplier circuit breaker half open, probing supplier")
+	}
+	result := d.fetchSupplierPayloads(ctx, runID, supplier, previous)
+	if result.report.Err != nil && ctx.Err() != nil {
+		breaker.cancel()
+	} else if from, to := breaker.record(result.report.Err); from != to {
+		if to == model.BreakerStateOpen {
+			d.logger.WithFields(fields).Warn("supplier circuit breaker opened")
+		} else {
+			d.logger.WithFields(fields).Info("supplier circuit breaker closed")
+		}
+	}
+	result.report.Breaker = breaker.status(supplier.Name).State
+	return result
+}
+
+func newSupplierFetchResult(supplier config.SupplierConfig) supplierFetchResult {
+	return supplierFetchResult{
+		report: model.SupplierLoadReport{
+			Supplier: supplier.Name,
+			URL:      utils.RedactURL(supplier.URL),
+			Status:   model.SupplierLoadStatusSuccess,
+		},
+		supplier: supplier,
 	}
-	return result, nil
 }
 
-func (d *DirectDataLoaderService) LoadData() error {
-	configs := strings.Split(d.configs, ",")
-	for _, config := range configs {
-		configSplit := strings.SplitN(config, ":", ConfigSubstringLimitSeparator)
-		if len(configSplit) != 2 {
-			panic("supplier config is broken, please check env variable SUPPLIER_CONFIG")
+// fetchSupplierPayloads fetches a single supplier and converts its records to
+// the supplier adapter, any failure is recorded in the result report. The
+// request is conditional when previous was fetched with the same supplier
+// config and adapter (using the ETag and Last-Modified of its payload),
+// previous is returned as unchanged when the supplier answers 304 so its
+// hotels are reused without decoding the payload again. Rejected records are
+// added to the dead letters of the run
+func (d *DirectDataLoaderService) fetchSupplierPayloads(ctx context.Context, runID string, supplier config.SupplierConfig, previous *supplierFetchResult) supplierFetchResult {
+	startedAt := time.Now()
+	result := newSupplierFetchResult(supplier)
+
+	d.mu.RLock()
+	adapters := d.adapters
+	archive := d.archive
+	d.mu.RUnlock()
+	supplierModel, err := adapters.Lookup(supplier.GetAdapter())
+	if err != nil {
+		return result.fail(err, startedAt)
+	}
+	result.adapter = supplierModel
+	result.adapterName = adapterVersion(adapters, supplier.GetAdapter())
+	var validators cacheValidators
+	if previous != nil && reflect.DeepEqual(previous.supplier, supplier) && reflect.DeepEqual(previous.adapter, supplierModel) {
+		validators = previous.validators
+	}
+
+	client, err := d.clientFor(supplier)
+	if err != nil {
+		return result.fail(err, startedAt)
+	}
+	defer client.Close()
+	result.validators, err = readSupplierPayloads(ctx, client, supplier, validators, d.supplierPayloadHandler(runID, supplier, &result, archive))
+	if errors.Is(err, errNotModified) {
+		unchanged := *previous
+		unchanged.report.Status = model.SupplierLoadStatusUnchanged
+		unchanged.report.Duration = time.Since(startedAt).String()
+		return unchanged
+	}
+	var pageErr *model.PageError
+	if errors.As(err, &pageErr) {
+		// a page decoded before failing is already reported
+		if pages := result.report.Pages; len(pages) > 0 && pages[len(pages)-1].Page == pageErr.Page {
+			pages[len(pages)-1].Error = pageErr.Err.Error()
+		} else {
+			result.report.Pages = append(result.report.Pages, model.SupplierPageReport{
+				Page:  pageErr.Page,
+				URL:   pageErr.URL,
+				Error: pageErr.Err.Error(),
+			})
+		}
+	}
+	if err != nil {
+		return result.fail(err, startedAt)
+	}
+	result.report.Duration = time.Since(startedAt).String()
+	return result
+}
+
+// supplierPayloadHandler returns the handler decoding the payloads of the
+// supplier into result, gzip and zstd compressed payloads are decompressed
+// first. The decompressed payloads are archived in the run when archive is
+// set, a payload that cannot be archived is still decoded
+func (d *DirectDataLoaderService) supplierPayloadHandler(runID string, supplier config.SupplierConfig, result *supplierFetchResult, archive *payloadArchive) payloadHandler {
+	d.mu.RLock()
+	limits := d.limits.forSupplier(supplier)
+	d.mu.RUnlock()
+
+	// records are bound to the adapter as they are streamed, a malformed
+	// record is rejected like a record the adapter cannot bind unless the
+	// feed format cannot skip it, then the supplier fails as a whole and so
+	// does a supplier exceeding its payload limits
+	decode, bind := feedDecoderFor(supplier)
+	guard := newPayloadGuard(limits)
+	// location is the url or file of the payload being decoded
+	var location string
+	onRecord := func(raw []byte, err error) error {
+		if err := guard.checkRecord(raw); err != nil {
+			return err
+		}
+		result.report.RecordsReceived++
+		var explicitSupplierTypeHotel model.HotelLoaderData
+		if err == nil {
+			explicitSupplierTypeHotel, err = bind(result.adapter, raw)
 		}
-		supplierIdentifier := configSplit[0]
-		url := configSplit[1]
-		results, err := readJsonFileFromUrl(url)
 		if err != nil {
 			d.logger.WithFields(logrus.Fields{
-				"supplier": supplierIdentifier,
-				"url":      url,
+				"run_id":   runID,
+				"supplier": supplier.Name,
+				"url":      result.report.URL,
 			}).Warn(err)
-			return err
+			result.report.RecordsRejected++
+			// truncated before the copy, a record can be up to max_record_bytes
+			record, truncated := repository.TruncateDeadLetterRecord(raw)
+			d.deadLetters.AddDeadLetter(model.DeadLetter{
+				RunID:      runID,
+				Supplier:   supplier.Name,
+				Location:   location,
+				Reason:     err.Error(),
+				Record:     string(record),
+				Truncated:  truncated,
+				RejectedAt: time.Now(),
+			})
+			return nil
 		}
-		supplierModel := d.hotelLoaderDataFactory.CreateSupplier(supplierIdentifier)
-		var newHotelData []model.HotelLoaderData
-		for _, result := range results {
-			explicitSupplierTypeHotel, err := supplierModel.ConvertToHotelLoaderData(result)
+		result.hotels = append(result.hotels, explicitSupplierTypeHotel)
+		return nil
+	}
+	return func(payloadLocation string, body io.Reader) (payloadResult, error) {
+		location = utils.RedactURL(payloadLocation)
+		payload, err := decompressPayload(body, limits.MaxPayloadBytes)
+		if err != nil {
+			return payloadResult{}, fmt.Errorf("cannot decompress payload: %w", err)
+		}
+		defer payload.Close()
+		body = payload
+		var archived *archivedPayloadWriter
+		if archive != nil {
+			archived, err = archive.createPayload(runID, supplier.Name, len(result.payloads)+1)
 			if err != nil {
-				d.logger.WithFields(logrus.Fields{
-					"supplier": supplierIdentifier,
-					"url":      url,
-				}).Warn(err)
-				continue
+				d.logArchiveError(runID, supplier.Name, err)
+			} else {
+				body = io.TeeReader(body, archived)
 			}
-			newHotelData = append(newHotelData, explicitSupplierTypeHotel)
 		}
-
-		for _, hotel := range newHotelData {
-			var existingData model.Hotel
-			hotelData := d.repo.GetHotelsByHotelIds([]string{hotel.GetId()})
-			if hotelData != nil {
-				existingData = *hotelData[0]
+		recordsBefore := result.report.RecordsReceived
+		values, err := decode(guard.reader(body), onRecord)
+		if archived != nil {
+			payload, archiveErr := archived.finish(location)
+			if archiveErr != nil {
+				d.logArchiveError(runID, supplier.Name, archiveErr)
+			} else {
+				result.payloads = append(result.payloads, payload)
+				result.archivedIn = runID
 			}
-			d.repo.InsertHotel(MergeData(existingData, hotel))
 		}
+		if guard.err != nil {
+			// the decoders wrap the error of a reader or a record handler
+			return payloadResult{}, guard.err
+		}
+		if err != nil {
+			return payloadResult{}, err
+		}
+		records := result.report.RecordsReceived - recordsBefore
+		if supplier.Pagination != nil {
+			result.report.Pages = append(result.report.Pages, model.SupplierPageReport{
+				Page:            len(result.report.Pages) + 1,
+				URL:             location,
+				RecordsReceived: records,
+			})
+		}
+		return payloadResult{records: records, values: values}, nil
 	}
-	return nil
+}
+
+// adapterVersion returns the adapter as name@version, an adapter given without
+// a version is resolved to the default version of the adapter
+func adapterVersion(adapters model.IHotelLoaderDataRegistry, adapter string) string {
+	if strings.Contains(adapter, model.AdapterVersionSeparator) {
+		return adapter
+	}
+	for _, info := range adapters.List() {
+		if info.Name == adapter && info.Default {
+			return info.Name + model.AdapterVersionSeparator + info.Version
+		}
+	}
+	return adapter
+}
+
+func supplierHeaders(supplier config.SupplierConfig) http.Header {
+	header := make(http.Header, len(supplier.Headers))
+	for key, value := range supplier.Headers {
+		header.Set(key, value)
+	}
+	return header
+}
+
+// mergeSupplierData merges the hotels of a single supplier with the data
+// already in the staging repository and returns the number of merged hotels
+func mergeSupplierData(staging repository.HotelRepository, newHotelData []model.HotelLoaderData) int {
+	for _, hotel := range newHotelData {
+		var existingData model.Hotel
+		hotelData := staging.GetHotelsByHotelIds([]string{hotel.GetId()})
+		if hotelData != nil {
+			existingData = *hotelData[0]
+		}
+		staging.InsertHotel(MergeData(existingData, hotel))
+	}
+	return len(newHotelData)
+}
+
+func (d *DirectDataLoaderService) logSupplierReport(runID string, supplierReport model.SupplierLoadReport) {
+	entry := d.logger.WithFields(logrus.Fields{
+		"run_id":           runID,
+		"supplier":         supplierReport.Supplier,
+		"url":              supplierReport.URL,
+		"status":           supplierReport.Status,
+		"records_received": supplierReport.RecordsReceived,
+		"records_rejected": supplierReport.RecordsRejected,
+		"hotels_merged":    supplierReport.HotelsMerged,
+		"duration":         supplierReport.Duration,
+		"breaker":          supplierReport.Breaker,
+	})
+	if supplierReport.Err != nil {
+		entry.Warn(supplierReport.Err)
+		return
+	}
+	entry.Info("supplier loaded")
 }
@@ -1,13 +1,22 @@
 package service
 
 import (
+	"context"
+	"datamerge/internal/config"
 	"datamerge/internal/model"
 	"datamerge/internal/repository"
+	"encoding/xml"
+	"errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var logger = logrus.New()
@@ -80,12 +89,21 @@ var (
 			}]`
 )
 
+// legacySuppliers builds the supplier configs from a SUPPLIER_CONFIG string
+func legacySuppliers(t *testing.T, configs string) []config.SupplierConfig {
+	suppliers, err := config.ParseLegacySupplierConfig(configs)
+	assert.Nil(t, err)
+	return suppliers
+}
+
 func TestDirectDataLoaderService_LoadDataWithBadurl(t *testing.T) {
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierA:badurl", repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:badurl"), repo, logger)
+	report, err := loader.LoadData(context.Background())
 	assert.Error(t, err)
-	assert.IsType(t, err, &model.HttpError{})
+	assert.IsType(t, err, &model.LoadError{})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+	assert.IsType(t, report.Suppliers[0].Err, &model.HttpError{})
 }
 
 func TestDirectDataLoaderService_UrlReturnNonJsonData(t *testing.T) {
@@ -95,10 +113,10 @@ func TestDirectDataLoaderService_UrlReturnNonJsonData(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierA:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+mockHttpServer.URL), repo, logger)
+	report, err := loader.LoadData(context.Background())
 	assert.Error(t, err)
-	assert.IsType(t, err, &model.JsonError{})
+	assert.IsType(t, report.Suppliers[0].Err, &model.JsonError{})
 }
 
 func TestDirectDataLoaderService_WithValidSupplierADataset(t *testing.T) {
@@ -108,8 +126,8 @@ func TestDirectDataLoaderService_WithValidSupplierADataset(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierA:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+mockHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
 	assert.Nil(t, err)
 	persistedData := repo.GetHotelsByHotelIds([]string{"iJhz"})
 	assert.Equal(t, len(persistedData), 1)
@@ -124,8 +142,8 @@ func TestDirectDataLoaderService_WithValidSupplierBDataset(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierB:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierB:"+mockHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
 	assert.Nil(t, err)
 	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
 	assert.Equal(t, len(persistedData), 1)
@@ -140,8 +158,8 @@ func TestDirectDataLoaderService_WithValidSupplierCDataset(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierC:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierC:"+mockHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
 	assert.Nil(t, err)
 	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
 	assert.Equal(t, len(persistedData), 1)
@@ -157,8 +175,8 @@ func TestDirectDataLoaderService_WithInvalidSupplierADataset(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierA:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+mockHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
 	assert.Nil(t, err)
 	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
 	assert.Equal(t, len(persistedData), 0)
@@ -172,8 +190,8 @@ func TestDirectDataLoaderService_WithInvalidSupplierBDataset(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierB:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierB:"+mockHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
 	assert.Nil(t, err)
 	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
 	assert.Equal(t, len(persistedData), 0)
@@ -187,9 +205,488 @@ func TestDirectDataLoaderService_WithInvalidSupplierCDataset(t *testing.T) {
 	}))
 	defer mockHttpServer.Close()
 	repo := repository.NewInMemoryHotelRepository()
-	loader := NewDirectDataLoaderService("supplierC:"+mockHttpServer.URL, repo, logger)
-	err := loader.LoadData()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierC:"+mockHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
 	assert.Nil(t, err)
 	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
 	assert.Equal(t, len(persistedData), 0)
 }
+
+func TestDirectDataLoaderService_ContinuesPastFailingSupplier(t *testing.T) {
+	failingHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer failingHttpServer.Close()
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierBDataset))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+failingHttpServer.URL+",supplierB:"+mockHttpServer.URL), repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Suppliers), 2)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+	assert.Equal(t, report.Suppliers[1].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, report.Suppliers[1].RecordsReceived, 1)
+	assert.Equal(t, report.Suppliers[1].HotelsMerged, 1)
+	assert.Len(t, report.FailedSuppliers(), 1)
+	assert.Equal(t, report.HotelsLoaded, 1)
+	assert.Equal(t, loader.LastReport(), report)
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+}
+
+func TestDirectDataLoaderService_ReportsRejectedRecords(t *testing.T) {
+	// supplierB dataset has an amenities object where supplierC expects a list
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierBDataset))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierC:"+mockHttpServer.URL), repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 1)
+	assert.Equal(t, report.Suppliers[0].RecordsRejected, 1)
+	assert.Equal(t, report.Suppliers[0].HotelsMerged, 0)
+}
+
+func TestDirectDataLoaderService_KeepsRejectedRecordsAsDeadLetters(t *testing.T) {
+	malformedRecord := `{"Id": "f8c9", "DestinationId": 5432, "Name":`
+	feed := `{"Id": "iJhz", "DestinationId": 5432, "Name": "Beach Villas Singapore"}` + "\n" + malformedRecord
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(feed))
+	}))
+	defer mockHttpServer.Close()
+	suppliers := []config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL + "?api_key=secret", Format: config.FeedFormatNdjson}}
+	loader := NewDirectDataLoaderService(suppliers, repository.NewInMemoryHotelRepository(), logger)
+	first, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	second, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.RunID, second.RunID)
+
+	deadLetters := loader.DeadLetters(model.DeadLetterFilter{})
+	assert.Equal(t, len(deadLetters), 2)
+	assert.Equal(t, deadLetters[0].RunID, second.RunID)
+	deadLetters = loader.DeadLetters(model.DeadLetterFilter{Supplier: "supplierA", RunID: first.RunID})
+	assert.Equal(t, len(deadLetters), 1)
+	assert.Equal(t, deadLetters[0].Record, malformedRecord)
+	assert.Equal(t, deadLetters[0].Location, mockHttpServer.URL+"?api_key=REDACTED")
+	assert.NotEmpty(t, deadLetters[0].Reason)
+
+	loader.SetDeadLetterCapacity(1)
+	assert.Equal(t, len(loader.DeadLetters(model.DeadLetterFilter{})), 1)
+}
+
+func TestDirectDataLoaderService_TruncatesLargeDeadLetters(t *testing.T) {
+	largeRecord := `{"Id": "f8c9", "Name": "` + strings.Repeat("x", 2*repository.MaxDeadLetterRecordBytes) + `"`
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(largeRecord))
+	}))
+	defer mockHttpServer.Close()
+	suppliers := []config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL, Format: config.FeedFormatNdjson}}
+	loader := NewDirectDataLoaderService(suppliers, repository.NewInMemoryHotelRepository(), logger)
+	loader.LoadData(context.Background())
+
+	deadLetters := loader.DeadLetters(model.DeadLetterFilter{})
+	assert.Equal(t, len(deadLetters), 1)
+	assert.Equal(t, deadLetters[0].Record, largeRecord[:repository.MaxDeadLetterRecordBytes])
+	assert.True(t, deadLetters[0].Truncated)
+}
+
+func TestDirectDataLoaderService_MergesLastDataOfSupplierFailingAfterSuccessfulLoad(t *testing.T) {
+	var requests int32
+	failingHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"Id": "f8c9", "DestinationId": 1122, "Name": "Hilton Shinjuku"}]`))
+	}))
+	defer failingHttpServer.Close()
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(supplierBDataset))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+failingHttpServer.URL+",supplierB:"+mockHttpServer.URL), repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{})
+	loader.SetBreakerConfig(SupplierBreakerConfig{Threshold: 1, Cooldown: time.Hour})
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(repo.GetAllHotels()), 2)
+
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusStale)
+	assert.IsType(t, report.Suppliers[0].Err, &model.HttpError{})
+	assert.Equal(t, report.Suppliers[0].HotelsMerged, 1)
+	assert.Equal(t, report.Suppliers[1].Status, model.SupplierLoadStatusSuccess)
+	assert.Len(t, report.FailedSuppliers(), 1)
+	assert.Equal(t, report.HotelsLoaded, 2)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{"f8c9"})), 1)
+
+	// the breaker of supplierA is open now, its last data is still merged
+	report, err = loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusStale)
+	assert.IsType(t, report.Suppliers[0].Err, &model.CircuitOpenError{})
+	assert.Equal(t, len(repo.GetAllHotels()), 2)
+	report, err = loader.ReloadSuppliers(context.Background(), []string{"supplierB"})
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusCached)
+	assert.Equal(t, len(repo.GetAllHotels()), 2)
+}
+
+func TestDirectDataLoaderService_KeepsRestoredCatalogUntilEverySupplierLoaded(t *testing.T) {
+	var supplierADown atomic.Value
+	supplierADown.Store(true)
+	supplierAHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if supplierADown.Load().(bool) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"Id": "f8c9", "DestinationId": 1122, "Name": "Hilton Shinjuku"}]`))
+	}))
+	defer supplierAHttpServer.Close()
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(supplierBDataset))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	repo.ReplaceAllHotels([]*model.Hotel{{ID: ValidHotelId, Name: "Beach Villas Singapore"}, {ID: "f8c9", Name: "Hilton Shinjuku"}})
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+supplierAHttpServer.URL+",supplierB:"+mockHttpServer.URL), repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{})
+	loader.MarkCatalogRestored()
+
+	report, err := loader.LoadData(context.Background())
+	var incompleteErr *model.IncompleteCatalogError
+	assert.True(t, errors.As(err, &incompleteErr))
+	assert.Equal(t, incompleteErr.Suppliers, []string{"supplierA"})
+	assert.Equal(t, report.HotelsLoaded, 0)
+	assert.Equal(t, repo.GetHotelsByHotelIds([]string{ValidHotelId})[0].Name, "Beach Villas Singapore")
+	assert.Equal(t, len(repo.GetAllHotels()), 2)
+
+	supplierADown.Store(false)
+	report, err = loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.HotelsLoaded, 2)
+	assert.Equal(t, repo.GetHotelsByHotelIds([]string{ValidHotelId})[0].Name, "Intercontinental")
+}
+
+func TestDirectDataLoaderService_KeepsCatalogWhenAllSuppliersFail(t *testing.T) {
+	failingHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer failingHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	repo.InsertHotel(&model.Hotel{ID: ValidHotelId, DestinationID: ValidDestinationId})
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+failingHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
+	assert.Error(t, err)
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+}
+
+func TestDirectDataLoaderService_MergesInConfiguredOrderRegardlessOfResponseTime(t *testing.T) {
+	// supplierA answers last but is configured first, its coordinates must win
+	// since the first non-zero coordinates are kept on merge
+	slowHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierADataset))
+	}))
+	defer slowHttpServer.Close()
+	fastHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(strings.Replace(supplierCDataset, "1.264751", "9.99", 1)))
+	}))
+	defer fastHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+slowHttpServer.URL+",supplierC:"+fastHttpServer.URL), repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Supplier, "supplierA")
+	assert.Equal(t, report.Suppliers[1].Supplier, "supplierC")
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+	assert.Equal(t, persistedData[0].Location.Lat, 1.264751)
+}
+
+func TestDirectDataLoaderService_FetchesWithinConcurrencyLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierBDataset))
+	}))
+	defer mockHttpServer.Close()
+	configs := make([]string, 0)
+	for i := 0; i < 6; i++ {
+		configs = append(configs, "supplierB:"+mockHttpServer.URL)
+	}
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, strings.Join(configs, ",")), repo, logger)
+	loader.SetConcurrency(2)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Suppliers), 6)
+	assert.Equal(t, atomic.LoadInt32(&maxInFlight), int32(2))
+}
+
+func TestDirectDataLoaderService_MergesByPriorityAndSkipsDisabledSuppliers(t *testing.T) {
+	supplierAHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierADataset))
+	}))
+	defer supplierAHttpServer.Close()
+	supplierCHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("X-Api-Key"), "key")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(strings.Replace(supplierCDataset, "1.264751", "9.99", 1)))
+	}))
+	defer supplierCHttpServer.Close()
+	disabled := false
+	suppliers := []config.SupplierConfig{
+		{Name: "supplierA", URL: supplierAHttpServer.URL, Priority: 2},
+		{Name: "supplierC", URL: supplierCHttpServer.URL, Priority: 1, Headers: map[string]string{"x-api-key": "key"}},
+		{Name: "supplierB", URL: "http://127.0.0.1:0", Enabled: &disabled},
+	}
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Suppliers), 2)
+	assert.Equal(t, report.Suppliers[0].Supplier, "supplierC")
+	assert.Equal(t, report.Suppliers[1].Supplier, "supplierA")
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, persistedData[0].Location.Lat, 9.99)
+}
+
+func TestDirectDataLoaderService_ReloadSuppliersOnlyFetchesGivenSuppliers(t *testing.T) {
+	var supplierACalls, supplierBCalls int32
+	supplierAHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&supplierACalls, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierADataset))
+	}))
+	defer supplierAHttpServer.Close()
+	supplierBHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&supplierBCalls, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(supplierBDataset))
+	}))
+	defer supplierBHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+supplierAHttpServer.URL), repo, logger)
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+
+	loader.SetSuppliers(legacySuppliers(t, "supplierA:"+supplierAHttpServer.URL+",supplierB:"+supplierBHttpServer.URL))
+	report, err := loader.ReloadSuppliers(context.Background(), []string{"supplierB"})
+	assert.Nil(t, err)
+	assert.Equal(t, atomic.LoadInt32(&supplierACalls), int32(1))
+	assert.Equal(t, atomic.LoadInt32(&supplierBCalls), int32(1))
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusCached)
+	assert.Equal(t, report.Suppliers[1].Status, model.SupplierLoadStatusSuccess)
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+	// supplierA data is still merged from its cached results
+	assert.Equal(t, persistedData[0].Location.City, "Singapore")
+	assert.Equal(t, len(persistedData[0].Images.Site), 4)
+
+	// removing a supplier drops its data from the catalog
+	loader.SetSuppliers(legacySuppliers(t, "supplierB:"+supplierBHttpServer.URL))
+	_, err = loader.ReloadSuppliers(context.Background(), []string{"supplierA"})
+	assert.Nil(t, err)
+	persistedData = repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Empty(t, persistedData[0].Location.City)
+	assert.Equal(t, atomic.LoadInt32(&supplierBCalls), int32(1))
+}
+
+func TestDirectDataLoaderService_WithUnknownAdapter(t *testing.T) {
+	suppliers := []config.SupplierConfig{{Name: "supplierZ", URL: "http://127.0.0.1:0"}}
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	assert.Error(t, loader.ValidateSuppliers(suppliers))
+	report, err := loader.LoadData(context.Background())
+	assert.Error(t, err)
+	assert.IsType(t, report.Suppliers[0].Err, &model.UnknownAdapterError{})
+}
+
+func TestDirectDataLoaderService_WithMappedSupplier(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[
+			{"code": "iJhz", "destination": 5432, "title": "Beach Villas", "facilities": ["Pool", "DryCleaning"]},
+			{"title": "record without id"}
+		]`))
+	}))
+	defer mockHttpServer.Close()
+	registry := model.NewHotelLoaderDataRegistry()
+	err := registry.RegisterMappings([]*model.HotelMapping{{
+		Name: "supplierD",
+		Fields: map[string]model.FieldMapping{
+			model.MappingFieldId:               {Path: "code"},
+			model.MappingFieldDestinationId:    {Path: "destination"},
+			model.MappingFieldName:             {Path: "title"},
+			model.MappingFieldGeneralAmenities: {Path: "facilities", Transforms: []string{"split_camel_case"}},
+		},
+	}})
+	assert.Nil(t, err)
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierD:"+mockHttpServer.URL), repo, logger)
+	loader.SetAdapterRegistry(registry)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsRejected, 1)
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+	assert.Equal(t, persistedData[0].DestinationID, ValidDestinationId)
+	assert.Equal(t, persistedData[0].Name, "Beach Villas")
+	assert.ElementsMatch(t, persistedData[0].Amenities.General, []string{"pool", "dry cleaning"})
+}
+
+func TestDirectDataLoaderService_WithNdjsonSupplier(t *testing.T) {
+	feed := strings.Join([]string{
+		`{"Id": "iJhz", "DestinationId": 5432, "Name": "Beach Villas Singapore"}`,
+		`{"Id": "f8c9", "DestinationId": 5432, "Name": `,
+		`{"Id": "SjyX", "DestinationId": 5432, "Name": "InterContinental"}`,
+	}, "\n")
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(feed))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	suppliers := []config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL, Format: config.FeedFormatNdjson}}
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 3)
+	assert.Equal(t, report.Suppliers[0].RecordsRejected, 1)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{ValidHotelId, "f8c9", "SjyX"})), 2)
+}
+
+func TestDirectDataLoaderService_WithCsvSupplier(t *testing.T) {
+	feed := "hotel id,destination,hotel name,lat,lng,amenities,room images\n" +
+		"iJhz,5432,Beach Villas Singapore,1.264751,103.824006,Pool | BusinessCenter,https://d2ey9sqrvkqdfs.cloudfront.net/0qZF/2.jpg|https://d2ey9sqrvkqdfs.cloudfront.net/0qZF/3.jpg\n" +
+		"f8c9,unknown,Hilton Shinjuku,,,,\n"
+	path := filepath.Join(t.TempDir(), "supplierE.csv")
+	assert.Nil(t, os.WriteFile(path, []byte(feed), 0o600))
+	registry := model.NewHotelLoaderDataRegistry()
+	err := registry.RegisterMappings([]*model.HotelMapping{{
+		Name: "supplierE",
+		Fields: map[string]model.FieldMapping{
+			model.MappingFieldId:               {Path: "hotel id"},
+			model.MappingFieldDestinationId:    {Path: "destination"},
+			model.MappingFieldName:             {Path: "hotel name"},
+			model.MappingFieldLat:              {Path: "lat"},
+			model.MappingFieldLng:              {Path: "lng"},
+			model.MappingFieldGeneralAmenities: {Path: "amenities", Transforms: []string{"split:|", "trim", "split_camel_case", "lower"}},
+			model.MappingFieldRoomImages:       {Path: "room images", Transforms: []string{"split:|"}},
+		},
+	}})
+	assert.Nil(t, err)
+	repo := repository.NewInMemoryHotelRepository()
+	suppliers := []config.SupplierConfig{{Name: "supplierE", URL: "file://" + path, Format: config.FeedFormatCsv}}
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	loader.SetAdapterRegistry(registry)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 2)
+	assert.Equal(t, report.Suppliers[0].RecordsRejected, 1)
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+	assert.Equal(t, persistedData[0].DestinationID, ValidDestinationId)
+	assert.Equal(t, persistedData[0].Location.Lat, 1.264751)
+	assert.ElementsMatch(t, persistedData[0].Amenities.General, []string{"pool", "business center"})
+	assert.Equal(t, len(persistedData[0].Images.Rooms), 2)
+}
+
+// hotelDataLoaderXmlSupplier is an XML bound adapter of a legacy supplier
+type hotelDataLoaderXmlSupplier struct {
+	ID            string   `xml:"code,attr"`
+	DestinationID int      `xml:"Destination"`
+	Name          string   `xml:"Name"`
+	Facilities    []string `xml:"Facilities>Facility"`
+}
+
+func (h *hotelDataLoaderXmlSupplier) DecodeHotelLoaderDataXml(data []byte) (model.HotelLoaderData, error) {
+	var result hotelDataLoaderXmlSupplier
+	err := xml.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (h *hotelDataLoaderXmlSupplier) ConvertToHotelLoaderData(t interface{}) (model.HotelLoaderData, error) {
+	return nil, errors.New("xml records only")
+}
+
+func (h *hotelDataLoaderXmlSupplier) GetId() string                    { return h.ID }
+func (h *hotelDataLoaderXmlSupplier) GetDestinationId() int            { return h.DestinationID }
+func (h *hotelDataLoaderXmlSupplier) GetName() string                  { return h.Name }
+func (h *hotelDataLoaderXmlSupplier) GetLocation() model.HotelLocation { return model.HotelLocation{} }
+func (h *hotelDataLoaderXmlSupplier) GetDescription() string           { return "" }
+func (h *hotelDataLoaderXmlSupplier) GetImages() model.HotelImages     { return model.HotelImages{} }
+func (h *hotelDataLoaderXmlSupplier) GetBookingConditions() []string   { return []string{} }
+func (h *hotelDataLoaderXmlSupplier) GetAmenities() model.HotelAmenities {
+	return model.HotelAmenities{General: h.Facilities}
+}
+
+func TestDirectDataLoaderService_WithXmlSupplier(t *testing.T) {
+	feed := `<?xml version="1.0"?>
+	<HotelDescriptiveContents>
+		<HotelDescriptiveContent code="iJhz">
+			<Destination>5432</Destination>
+			<Name>Beach Villas Singapore</Name>
+			<Facilities><Facility>pool</Facility><Facility>wifi</Facility></Facilities>
+		</HotelDescriptiveContent>
+		<HotelDescriptiveContent code="f8c9">
+			<Destination>unknown</Destination>
+		</HotelDescriptiveContent>
+	</HotelDescriptiveContents>`
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(feed))
+	}))
+	defer mockHttpServer.Close()
+	registry := model.NewHotelLoaderDataRegistry()
+	assert.Nil(t, registry.Register("gds", "v1", func() model.HotelLoaderData {
+		return &hotelDataLoaderXmlSupplier{}
+	}))
+	repo := repository.NewInMemoryHotelRepository()
+	suppliers := []config.SupplierConfig{{
+		Name:          "gds",
+		URL:           mockHttpServer.URL,
+		Format:        config.FeedFormatXml,
+		RecordElement: "HotelDescriptiveContent",
+	}}
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	loader.SetAdapterRegistry(registry)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 2)
+	assert.Equal(t, report.Suppliers[0].RecordsRejected, 1)
+	persistedData := repo.GetHotelsByHotelIds([]string{ValidHotelId})
+	assert.Equal(t, len(persistedData), 1)
+	assert.Equal(t, persistedData[0].DestinationID, ValidDestinationId)
+	assert.Equal(t, persistedData[0].Name, "Beach Villas Singapore")
+	assert.ElementsMatch(t, persistedData[0].Amenities.General, []string{"pool", "wifi"})
+}
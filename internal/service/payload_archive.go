@@ -0,0 +1,334 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"datamerge/internal/model"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultArchiveRetention = 7 * 24 * time.Hour
+	archiveManifestFile     = "manifest.json"
+	// runIDTimeLayout is the layout of the start time leading every run ID
+	runIDTimeLayout = "20060102T150405Z"
+)
+
+// IPayloadArchive exposes the archived load runs and replays them
+type IPayloadArchive interface {
+	ArchivedRuns() ([]model.ArchiveManifest, error)
+	ReplayRun(ctx context.Context, runID string, currentRules bool) (*model.LoadReport, error)
+}
+
+// PayloadArchiveConfig controls the payload archive, an empty Dir disables it
+// Runs started more than Retention ago and runs beyond the MaxRuns most recent
+// ones are deleted, a zero Retention or MaxRuns disables that limit
+type PayloadArchiveConfig struct {
+	Dir       string
+	Retention time.Duration
+	MaxRuns   int
+}
+
+// payloadArchive keeps the payloads of every load run in a directory named
+// after the run ID, with a sub directory per supplier holding its payloads
+// gzip compressed and a manifest describing the run. Every run directory is
+// self contained, the payloads a run reuses from a previous run (unchanged or
+// cached suppliers) are linked into it so retention can drop any run
+type payloadArchive struct {
+	config PayloadArchiveConfig
+	now    func() time.Time
+}
+
+func newPayloadArchive(config PayloadArchiveConfig) *payloadArchive {
+	if config.Dir == "" {
+		return nil
+	}
+	return &payloadArchive{config: config, now: time.Now}
+}
+
+func (a *payloadArchive) runDir(runID string) string {
+	return filepath.Join(a.config.Dir, runID)
+}
+
+// createPayload creates the file of the index-th payload of the supplier in
+// the run, payloads are numbered from 1
+func (a *payloadArchive) createPayload(runID, supplier string, index int) (*archivedPayloadWriter, error) {
+	name := filepath.Join(archiveDirName(supplier), fmt.Sprintf("%05d.gz", index))
+	path := filepath.Join(a.runDir(runID), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &archivedPayloadWriter{
+		name: filepath.ToSlash(name),
+		path: path,
+		file: f,
+		gzip: gzip.NewWriter(f),
+		hash: sha256.New(),
+	}, nil
+}
+
+// linkPayloads makes the payloads archived by the run from available in the
+// run to as well, they are hard linked when possible and copied otherwise.
+// A file already archived under the same name in the run to is replaced
+func (a *payloadArchive) linkPayloads(from, to string, payloads []model.ArchivedPayload) error {
+	for _, payload := range payloads {
+		source := filepath.Join(a.runDir(from), filepath.FromSlash(payload.File))
+		target := filepath.Join(a.runDir(to), filepath.FromSlash(payload.File))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.Link(source, target); err != nil {
+			if err := copyFile(source, target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removePayloads removes the payloads archived by the run, it is used to drop
+// the payloads of a fetch that failed part way through
+func (a *payloadArchive) removePayloads(runID string, payloads []model.ArchivedPayload) error {
+	for _, payload := range payloads {
+		err := os.Remove(filepath.Join(a.runDir(runID), filepath.FromSlash(payload.File)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeManifest writes the manifest of a run, the manifest is written last
+// so a run without a manifest is an incomplete run
+func (a *payloadArchive) writeManifest(manifest *model.ArchiveManifest) error {
+	dir := a.runDir(manifest.RunID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, archiveManifestFile+".tmp")
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, archiveManifestFile))
+}
+
+// readManifest returns the manifest of an archived run, an UnknownRunError is
+// returned when the run has no manifest
+func (a *payloadArchive) readManifest(runID string) (*model.ArchiveManifest, error) {
+	if runID == "" || runID != filepath.Base(runID) || strings.HasPrefix(runID, ".") {
+		return nil, &model.UnknownRunError{RunID: runID}
+	}
+	content, err := os.ReadFile(filepath.Join(a.runDir(runID), archiveManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &model.UnknownRunError{RunID: runID}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest model.ArchiveManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, &model.ArchiveError{Path: archiveManifestFile, Err: err}
+	}
+	return &manifest, nil
+}
+
+// manifests returns the manifest of every archived run, most recent first
+func (a *payloadArchive) manifests() ([]model.ArchiveManifest, error) {
+	manifests := make([]model.ArchiveManifest, 0)
+	runs, err := a.runs()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		manifest, err := a.readManifest(run.id)
+		if err != nil {
+			// runs still in progress or left incomplete have no manifest
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+	return manifests, nil
+}
+
+// prune deletes the runs past the retention of the archive, the run keep is
+// never deleted. The names of the deleted runs are returned
+func (a *payloadArchive) prune(keep string) ([]string, error) {
+	runs, err := a.runs()
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for i, run := range runs {
+		expired := a.config.Retention > 0 && a.now().Sub(run.startedAt) > a.config.Retention
+		if run.id == keep || !(expired || (a.config.MaxRuns > 0 && i >= a.config.MaxRuns)) {
+			continue
+		}
+		if err := os.RemoveAll(a.runDir(run.id)); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, run.id)
+	}
+	return deleted, nil
+}
+
+type archivedRun struct {
+	id        string
+	startedAt time.Time
+}
+
+// runs returns the run directories of the archive, most recent first. Only
+// directories named after a run ID are considered part of the archive, they
+// are ordered by the start time of their manifest as the run ID only holds
+// the second the run started at
+func (a *payloadArchive) runs() ([]archivedRun, error) {
+	entries, err := os.ReadDir(a.config.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var runs []archivedRun
+	for _, entry := range entries {
+		if !entry.IsDir() || len(entry.Name()) < len(runIDTimeLayout) {
+			continue
+		}
+		startedAt, err := time.Parse(runIDTimeLayout, entry.Name()[:len(runIDTimeLayout)])
+		if err != nil {
+			continue
+		}
+		if manifest, err := a.readManifest(entry.Name()); err == nil {
+			startedAt = manifest.StartedAt
+		}
+		runs = append(runs, archivedRun{id: entry.Name(), startedAt: startedAt})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].startedAt.Equal(runs[j].startedAt) {
+			return runs[i].startedAt.After(runs[j].startedAt)
+		}
+		return runs[i].id > runs[j].id
+	})
+	return runs, nil
+}
+
+// verifyPayload checks the archived payload against the size and checksum
+// recorded in the manifest
+func (a *payloadArchive) verifyPayload(runID string, payload model.ArchivedPayload) error {
+	path := filepath.Join(a.runDir(runID), filepath.FromSlash(payload.File))
+	f, err := os.Open(path)
+	if err != nil {
+		return &model.ArchiveError{Path: payload.File, Err: err}
+	}
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return &model.ArchiveError{Path: payload.File, Err: err}
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return &model.ArchiveError{Path: payload.File, Err: err}
+	}
+	if size != payload.Bytes || hex.EncodeToString(hash.Sum(nil)) != payload.SHA256 {
+		return &model.ArchiveError{Path: payload.File, Err: errors.New("checksum mismatch")}
+	}
+	return nil
+}
+
+// archivedPayloadWriter archives a payload while it is decoded, a failed
+// write does not fail the decoding, it is reported by finish instead
+type archivedPayloadWriter struct {
+	name  string
+	path  string
+	file  *os.File
+	gzip  *gzip.Writer
+	hash  hash.Hash
+	bytes int64
+	err   error
+}
+
+func (w *archivedPayloadWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		_, w.err = w.gzip.Write(p)
+		w.hash.Write(p)
+		w.bytes += int64(len(p))
+	}
+	return len(p), nil
+}
+
+// finish closes the archived payload and returns its manifest entry, the
+// file is removed when the payload could not be archived
+func (w *archivedPayloadWriter) finish(location string) (model.ArchivedPayload, error) {
+	err := w.err
+	if closeErr := w.gzip.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(w.path)
+		return model.ArchivedPayload{}, err
+	}
+	return model.ArchivedPayload{
+		File:     w.name,
+		Location: location,
+		Bytes:    w.bytes,
+		SHA256:   hex.EncodeToString(w.hash.Sum(nil)),
+	}, nil
+}
+
+// archiveDirName returns a directory name for the supplier, any character
+// that is not safe in a file name is replaced
+func archiveDirName(supplier string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, strings.TrimLeft(supplier, "."))
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newArchivingLoader returns a loader of supplierA and supplierB archiving its
+// payloads in dir, supplierA serves feedA and supplierB serves supplierBDataset
+func newArchivingLoader(t *testing.T, dir string, feedA *atomic.Value) (*DirectDataLoaderService, *repository.InMemoryHotelRepository) {
+	serverA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feedA.Load().(string)))
+	}))
+	t.Cleanup(serverA.Close)
+	serverB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(supplierBDataset))
+	}))
+	t.Cleanup(serverB.Close)
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+serverA.URL+",supplierB:"+serverB.URL), repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	loader.SetPayloadArchive(PayloadArchiveConfig{Dir: dir, Retention: DefaultArchiveRetention})
+	return loader, repo
+}
+
+func TestDirectDataLoaderService_ArchivesPayloadsOfEveryRun(t *testing.T) {
+	var feedA atomic.Value
+	feedA.Store(supplierADataset)
+	loader, _ := newArchivingLoader(t, t.TempDir(), &feedA)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+
+	manifests, err := loader.ArchivedRuns()
+	assert.Nil(t, err)
+	assert.Equal(t, len(manifests), 1)
+	manifest := manifests[0]
+	assert.Equal(t, manifest.RunID, report.RunID)
+	assert.Equal(t, len(manifest.Suppliers), 2)
+	supplierA := manifest.Suppliers[0]
+	assert.Equal(t, supplierA.Supplier, "supplierA")
+	assert.Equal(t, supplierA.Adapter, "supplierA@v1")
+	assert.Equal(t, supplierA.Status, model.SupplierLoadStatusSuccess)
+	assert.True(t, supplierA.Merged)
+	assert.Equal(t, len(supplierA.Payloads), 1)
+	assert.Equal(t, supplierA.Payloads[0].File, "supplierA/00001.gz")
+	assert.Equal(t, supplierA.Payloads[0].Bytes, int64(len(supplierADataset)))
+	assert.Len(t, supplierA.Payloads[0].SHA256, 64)
+}
+
+func TestDirectDataLoaderService_ReplayRunRebuildsCatalog(t *testing.T) {
+	var feedA atomic.Value
+	feedA.Store(supplierADataset)
+	loader, repo := newArchivingLoader(t, t.TempDir(), &feedA)
+	first, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	catalog := repo.GetHotelsByHotelIds([]string{ValidHotelId})[0]
+
+	feedA.Store(`[{"Id": "f8c9", "DestinationId": 5432, "Name": "Hilton Shinjuku"}]`)
+	_, err = loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{"f8c9"})), 1)
+
+	report, err := loader.ReplayRun(context.Background(), first.RunID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, report.ReplayOf, first.RunID)
+	assert.NotEqual(t, report.RunID, first.RunID)
+	assert.Equal(t, report.HotelsLoaded, 1)
+	assert.Equal(t, len(report.Suppliers), 2)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{"f8c9"})), 0)
+	// amenities are merged through a set, their order is not significant
+	replayed := repo.GetHotelsByHotelIds([]string{ValidHotelId})[0]
+	assert.ElementsMatch(t, replayed.Amenities.General, catalog.Amenities.General)
+	assert.ElementsMatch(t, replayed.Amenities.Room, catalog.Amenities.Room)
+	replayed.Amenities, catalog.Amenities = model.HotelAmenities{}, model.HotelAmenities{}
+	assert.Equal(t, replayed, catalog)
+
+	// the replay is not archived, and the next load merges the suppliers again
+	manifests, _ := loader.ArchivedRuns()
+	assert.Equal(t, len(manifests), 2)
+	_, err = loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{"f8c9"})), 1)
+}
+
+func TestDirectDataLoaderService_ReplayRunChecksPayloads(t *testing.T) {
+	dir := t.TempDir()
+	var feedA atomic.Value
+	feedA.Store(supplierADataset)
+	loader, repo := newArchivingLoader(t, dir, &feedA)
+	first, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, first.RunID, "supplierA", "00001.gz"), []byte("tampered"), 0o644))
+	repo.ReplaceAllHotels(nil)
+
+	report, err := loader.ReplayRun(context.Background(), first.RunID, false)
+	assert.Nil(t, err)
+	var archiveErr *model.ArchiveError
+	assert.True(t, errors.As(report.Suppliers[0].Err, &archiveErr))
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+	assert.Equal(t, report.Suppliers[1].Status, model.SupplierLoadStatusSuccess)
+
+	_, err = loader.ReplayRun(context.Background(), "20240501T101500Z-9f2c41d0", false)
+	assert.IsType(t, err, &model.UnknownRunError{})
+	_, err = loader.ReplayRun(context.Background(), "../"+first.RunID, false)
+	assert.IsType(t, err, &model.UnknownRunError{})
+}
+
+func TestDirectDataLoaderService_ReplayRunOfStaleSupplier(t *testing.T) {
+	var feedA atomic.Value
+	feedA.Store(supplierADataset)
+	loader, repo := newArchivingLoader(t, t.TempDir(), &feedA)
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+
+	// the malformed payload is archived under the same name as the payload
+	// of the previous run the stale supplier is merged from
+	feedA.Store(`[{"Id": "f8c9", "DestinationId": 5432, "Name": "Hilton`)
+	second, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, second.Suppliers[0].Status, model.SupplierLoadStatusStale)
+
+	repo.ReplaceAllHotels(nil)
+	report, err := loader.ReplayRun(context.Background(), second.RunID, false)
+	assert.Nil(t, err)
+	assert.Nil(t, report.Suppliers[0].Err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, report.HotelsLoaded, 1)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{ValidHotelId})), 1)
+}
+
+func TestDirectDataLoaderService_ReplayRunWithoutArchive(t *testing.T) {
+	loader := NewDirectDataLoaderService(nil, repository.NewInMemoryHotelRepository(), logger)
+	_, err := loader.ReplayRun(context.Background(), "20240501T101500Z-9f2c41d0", false)
+	assert.Equal(t, err, model.ErrArchiveDisabled)
+	_, err = loader.ArchivedRuns()
+	assert.Equal(t, err, model.ErrArchiveDisabled)
+}
+
+func TestDirectDataLoaderService_ArchiveLinksUnchangedPayloads(t *testing.T) {
+	dir := t.TempDir()
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(conditionalSupplierFeed))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL}}, repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	loader.SetPayloadArchive(PayloadArchiveConfig{Dir: dir, MaxRuns: 1})
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	second, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, second.Suppliers[0].Status, model.SupplierLoadStatusUnchanged)
+
+	// the first run is pruned, the second one holds the unchanged payload
+	manifests, err := loader.ArchivedRuns()
+	assert.Nil(t, err)
+	assert.Equal(t, len(manifests), 1)
+	assert.Equal(t, manifests[0].RunID, second.RunID)
+	assert.Equal(t, manifests[0].Suppliers[0].Status, model.SupplierLoadStatusUnchanged)
+	repo.ReplaceAllHotels(nil)
+	report, err := loader.ReplayRun(context.Background(), second.RunID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, report.HotelsLoaded, 1)
+}
+
+func TestPayloadArchive_Prune(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20240501T000000Z-00000001", "20240505T000000Z-00000002", "20240506T000000Z-00000003", "20240507T000000Z-00000004", "backups"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, name), 0o755))
+	}
+	archive := newPayloadArchive(PayloadArchiveConfig{Dir: dir, Retention: 7 * 24 * time.Hour, MaxRuns: 2})
+	archive.now = func() time.Time { return time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC) }
+
+	deleted, err := archive.prune("20240505T000000Z-00000002")
+	assert.Nil(t, err)
+	assert.Equal(t, deleted, []string{"20240501T000000Z-00000001"})
+	deleted, err = archive.prune("20240507T000000Z-00000004")
+	assert.Nil(t, err)
+	assert.Equal(t, deleted, []string{"20240505T000000Z-00000002"})
+	_, err = os.Stat(filepath.Join(dir, "backups"))
+	assert.Nil(t, err)
+}
+
+func TestReplayedSuppliers(t *testing.T) {
+	manifest := &model.ArchiveManifest{Suppliers: []model.ArchivedSupplier{
+		{Supplier: "supplierA", Adapter: "supplierA@v1", Merged: true},
+		{Supplier: "supplierB", Adapter: "supplierB@v1", Merged: true},
+		{Supplier: "supplierC", Adapter: "supplierC@v1", Merged: false},
+		{Supplier: "supplierD", Adapter: "supplierD@v1", Merged: true},
+	}}
+	current := []config.SupplierConfig{{Name: "supplierB"}, {Name: "supplierA", Adapter: "supplierA@v2"}, {Name: "supplierC"}}
+
+	replayed := replayedSuppliers(manifest, current, false)
+	assert.Equal(t, len(replayed), 3)
+	assert.Equal(t, replayed[0].Adapter, "supplierA@v1")
+	assert.Equal(t, replayed[2].Supplier, "supplierD")
+
+	replayed = replayedSuppliers(manifest, current, true)
+	assert.Equal(t, replayed, []model.ArchivedSupplier{
+		{Supplier: "supplierB", Adapter: "supplierB", Merged: true},
+		{Supplier: "supplierA", Adapter: "supplierA@v2", Merged: true},
+	})
+}
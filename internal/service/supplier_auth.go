@@ -0,0 +1,98 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"datamerge/internal/config"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// authenticateRequest applies the supplier auth configuration to req, the
+// secrets are resolved on every request so rotated secret files are picked
+// up. The resolved secrets are returned so they can be redacted from errors
+func authenticateRequest(req *http.Request, auth *config.SupplierAuthConfig, now time.Time) ([]string, error) {
+	if auth == nil {
+		return nil, nil
+	}
+	var secrets []string
+	resolve := func(name string, secret config.SecretRef) (string, error) {
+		value, err := secret.Resolve()
+		if err != nil {
+			return "", fmt.Errorf("supplier auth %s: %w", name, err)
+		}
+		secrets = append(secrets, value)
+		return value, nil
+	}
+	for header, secret := range auth.Headers {
+		value, err := resolve("header "+header, secret)
+		if err != nil {
+			return secrets, err
+		}
+		req.Header.Set(header, value)
+	}
+	switch auth.GetType() {
+	case config.SupplierAuthBearer:
+		token, err := resolve("token", auth.Token)
+		if err != nil {
+			return secrets, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case config.SupplierAuthBasic:
+		password, err := resolve("password", auth.Password)
+		if err != nil {
+			return secrets, err
+		}
+		req.SetBasicAuth(auth.Username, password)
+	case config.SupplierAuthQuery:
+		token, err := resolve("token", auth.Token)
+		if err != nil {
+			return secrets, err
+		}
+		query := req.URL.Query()
+		query.Set(auth.QueryParam, token)
+		req.URL.RawQuery = query.Encode()
+	case config.SupplierAuthHmac:
+		secret, err := resolve("secret", auth.Secret)
+		if err != nil {
+			return secrets, err
+		}
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(auth.GetTimestampHeader(), timestamp)
+		req.Header.Set(auth.GetSignatureHeader(), signRequest(secret, req.Method, req.URL.RequestURI(), timestamp))
+		if auth.KeyId != "" {
+			req.Header.Set(config.DefaultHmacKeyIdHeader, auth.KeyId)
+		}
+	}
+	return secrets, nil
+}
+
+// authHeaders returns the names of the headers authenticateRequest sets
+func authHeaders(auth *config.SupplierAuthConfig) []string {
+	if auth == nil {
+		return nil
+	}
+	headers := make([]string, 0, len(auth.Headers)+3)
+	for header := range auth.Headers {
+		headers = append(headers, header)
+	}
+	switch auth.GetType() {
+	case config.SupplierAuthBearer, config.SupplierAuthBasic:
+		headers = append(headers, "Authorization")
+	case config.SupplierAuthHmac:
+		headers = append(headers, auth.GetTimestampHeader(), auth.GetSignatureHeader(), config.DefaultHmacKeyIdHeader)
+	}
+	return headers
+}
+
+// signRequest returns the hex encoded HMAC-SHA256 of the request method, the
+// request uri (path and query) and the timestamp, separated by newlines
+func signRequest(secret, method, requestURI, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, requestURI, timestamp}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"datamerge/internal/model"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSupplierBreakerThreshold = 5
+	DefaultSupplierBreakerCooldown  = time.Minute
+)
+
+// SupplierBreakerConfig controls the circuit breaker of every supplier, the
+// breaker opens after Threshold consecutive failed fetches and lets a probe
+// fetch through once Cooldown has elapsed. A zero Threshold disables it
+type SupplierBreakerConfig struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// ISupplierBreakers exposes the circuit breaker of every supplier
+type ISupplierBreakers interface {
+	Breakers() []model.SupplierBreakerStatus
+}
+
+func DefaultSupplierBreakerConfig() SupplierBreakerConfig {
+	return SupplierBreakerConfig{
+		Threshold: DefaultSupplierBreakerThreshold,
+		Cooldown:  DefaultSupplierBreakerCooldown,
+	}
+}
+
+// supplierBreaker is the circuit breaker of a single supplier. A closed breaker
+// lets every fetch through and counts the consecutive failures, an open one
+// fails the fetches straight away until the cooldown is over, then it turns
+// half open and lets a single probe through: a successful probe closes the
+// breaker again while a failed one opens it for another cooldown
+type supplierBreaker struct {
+	mu       sync.Mutex
+	config   SupplierBreakerConfig
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+func newSupplierBreaker(config SupplierBreakerConfig) *supplierBreaker {
+	return &supplierBreaker{
+		config: config,
+		state:  model.BreakerStateClosed,
+		now:    time.Now,
+	}
+}
+
+func (b *supplierBreaker) setConfig(config SupplierBreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = config
+	if config.Threshold <= 0 {
+		b.state = model.BreakerStateClosed
+		b.probing = false
+	}
+}
+
+// allow reports whether the supplier can be fetched, probe is true when the
+// fetch is the probe of a half open breaker. A CircuitOpenError is returned
+// when the supplier cannot be fetched
+func (b *supplierBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.config.Threshold <= 0 {
+		return false, nil
+	}
+	retryAt := b.openedAt.Add(b.config.Cooldown)
+	switch b.state {
+	case model.BreakerStateOpen:
+		if b.now().Before(retryAt) {
+			return false, &model.CircuitOpenError{Failures: b.failures, RetryAt: retryAt}
+		}
+		b.state = model.BreakerStateHalfOpen
+	case model.BreakerStateHalfOpen:
+		if b.probing {
+			return false, &model.CircuitOpenError{Failures: b.failures, RetryAt: retryAt}
+		}
+	}
+	b.probing = b.state == model.BreakerStateHalfOpen
+	return b.probing, nil
+}
+
+// cancel releases an allowed fetch that was aborted without an outcome
+func (b *supplierBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// record records the outcome of an allowed fetch and returns the previous and
+// the new state of the breaker
+func (b *supplierBreaker) record(err error) (from, to string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from = b.state
+	b.probing = false
+	if err == nil {
+		b.state = model.BreakerStateClosed
+		b.failures = 0
+		return from, b.state
+	}
+	b.failures++
+	if b.config.Threshold > 0 && (b.state == model.BreakerStateHalfOpen || b.failures >= b.config.Threshold) {
+		b.state = model.BreakerStateOpen
+		b.openedAt = b.now()
+	}
+	return from, b.state
+}
+
+// status returns the state of the breaker, an open breaker whose cooldown is
+// over is reported half open as its next fetch is a probe
+func (b *supplierBreaker) status(supplier string) model.SupplierBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := model.SupplierBreakerStatus{
+		Supplier:            supplier,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != model.BreakerStateClosed {
+		status.OpenedAt = b.openedAt
+		status.RetryAt = b.openedAt.Add(b.config.Cooldown)
+		if b.state == model.BreakerStateOpen && !b.now().Before(status.RetryAt) {
+			status.State = model.BreakerStateHalfOpen
+		}
+	}
+	return status
+}
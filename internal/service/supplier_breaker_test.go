@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSupplierBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	breaker := newSupplierBreaker(SupplierBreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	breaker.now = func() time.Time { return now }
+	failure := errors.New("http request error")
+
+	for _, err := range []error{failure, nil, failure} {
+		_, allowErr := breaker.allow()
+		assert.Nil(t, allowErr)
+		breaker.record(err)
+	}
+	assert.Equal(t, breaker.status("supplierA").State, model.BreakerStateClosed)
+	_, err := breaker.allow()
+	assert.Nil(t, err)
+	from, to := breaker.record(failure)
+	assert.Equal(t, from, model.BreakerStateClosed)
+	assert.Equal(t, to, model.BreakerStateOpen)
+
+	_, err = breaker.allow()
+	var openErr *model.CircuitOpenError
+	assert.ErrorAs(t, err, &openErr)
+	assert.Equal(t, openErr.Failures, 2)
+	assert.Equal(t, openErr.RetryAt, now.Add(time.Minute))
+}
+
+func TestSupplierBreaker_HalfOpenProbe(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	breaker := newSupplierBreaker(SupplierBreakerConfig{Threshold: 1, Cooldown: time.Minute})
+	breaker.now = func() time.Time { return now }
+	breaker.allow()
+	breaker.record(errors.New("http request error"))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, breaker.status("supplierA").State, model.BreakerStateHalfOpen)
+	probe, err := breaker.allow()
+	assert.Nil(t, err)
+	assert.True(t, probe)
+	// a single probe is let through at a time
+	_, err = breaker.allow()
+	assert.Error(t, err)
+
+	// a failed probe opens the breaker for another cooldown
+	_, to := breaker.record(errors.New("http request error"))
+	assert.Equal(t, to, model.BreakerStateOpen)
+	_, err = breaker.allow()
+	assert.Error(t, err)
+
+	now = now.Add(time.Minute)
+	probe, _ = breaker.allow()
+	assert.True(t, probe)
+	_, to = breaker.record(nil)
+	assert.Equal(t, to, model.BreakerStateClosed)
+	assert.Equal(t, breaker.status("supplierA").ConsecutiveFailures, 0)
+}
+
+func TestSupplierBreaker_DisabledWithoutThreshold(t *testing.T) {
+	breaker := newSupplierBreaker(SupplierBreakerConfig{})
+	for i := 0; i < 10; i++ {
+		_, err := breaker.allow()
+		assert.Nil(t, err)
+		breaker.record(errors.New("http request error"))
+	}
+	assert.Equal(t, breaker.status("supplierA").State, model.BreakerStateClosed)
+}
+
+func TestDirectDataLoaderService_OpenBreakerSkipsSupplier(t *testing.T) {
+	requests := 0
+	failing := true
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(conditionalSupplierFeed))
+	}))
+	defer mockHttpServer.Close()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL}}, repository.NewInMemoryHotelRepository(), logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	loader.SetBreakerConfig(SupplierBreakerConfig{Threshold: 2, Cooldown: 50 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		loader.LoadData(context.Background())
+	}
+	report, err := loader.LoadData(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, requests, 2)
+	var openErr *model.CircuitOpenError
+	assert.ErrorAs(t, report.Suppliers[0].Err, &openErr)
+	assert.Equal(t, report.Suppliers[0].Breaker, model.BreakerStateOpen)
+	assert.Equal(t, loader.Breakers()[0].State, model.BreakerStateOpen)
+
+	time.Sleep(50 * time.Millisecond)
+	failing = false
+	report, err = loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, requests, 3)
+	assert.Equal(t, report.Suppliers[0].Breaker, model.BreakerStateClosed)
+}
+
+func TestDirectDataLoaderService_CancelledLoadDoesNotCountAsFailure(t *testing.T) {
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: "http://localhost/hotels"}}, repository.NewInMemoryHotelRepository(), logger)
+	loader.SetBreakerConfig(SupplierBreakerConfig{Threshold: 1, Cooldown: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	loader.LoadData(ctx)
+	assert.Equal(t, loader.Breakers()[0].State, model.BreakerStateClosed)
+	assert.Equal(t, loader.Breakers()[0].ConsecutiveFailures, 0)
+}
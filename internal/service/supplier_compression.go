@@ -0,0 +1,51 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+// SupplierAcceptEncoding is sent to the suppliers so they can serve their
+// payloads compressed, the payloads are decompressed by decompressPayload
+const SupplierAcceptEncoding = "gzip, zstd"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompressPayload detects gzip and zstd payloads from their magic bytes and
+// returns a reader of the decompressed payload, any other payload is returned
+// as is. Detecting the compression from the content instead of the file
+// extension or the Content-Encoding header covers .gz dumps served as plain
+// files as well as compressed http responses. A positive maxBytes bounds the
+// memory and the window the zstd decoder allocates, so a crafted frame cannot
+// allocate a large window before the payload limit applies
+func decompressPayload(body io.Reader, maxBytes int64) (io.ReadCloser, error) {
+	reader := bufio.NewReader(body)
+	magic, _ := reader.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(reader)
+	case bytes.HasPrefix(magic, zstdMagic):
+		options := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+		if maxBytes > 0 {
+			window := uint64(maxBytes)
+			if window < zstd.MinWindowSize {
+				window = zstd.MinWindowSize
+			} else if window > zstd.MaxWindowSize {
+				window = zstd.MaxWindowSize
+			}
+			options = append(options, zstd.WithDecoderMaxMemory(uint64(maxBytes)), zstd.WithDecoderMaxWindow(window))
+		}
+		decoder, err := zstd.NewReader(reader, options...)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return io.NopCloser(reader), nil
+}
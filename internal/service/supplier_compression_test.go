@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/repository"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func gzipPayload(t *testing.T, payload string) []byte {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(payload))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	return compressed.Bytes()
+}
+
+func zstdPayload(t *testing.T, payload string) []byte {
+	encoder, err := zstd.NewWriter(nil)
+	assert.Nil(t, err)
+	defer encoder.Close()
+	return encoder.EncodeAll([]byte(payload), nil)
+}
+
+func TestDecompressPayload(t *testing.T) {
+	payloads := map[string][]byte{
+		"plain": []byte(supplierADataset),
+		"gzip":  gzipPayload(t, supplierADataset),
+		"zstd":  zstdPayload(t, supplierADataset),
+	}
+	for name, payload := range payloads {
+		reader, err := decompressPayload(bytes.NewReader(payload), 0)
+		assert.Nil(t, err, name)
+		decompressed, err := io.ReadAll(reader)
+		assert.Nil(t, err, name)
+		assert.Nil(t, reader.Close(), name)
+		assert.Equal(t, string(decompressed), supplierADataset, name)
+	}
+}
+
+func TestDecompressPayload_ShortAndCorruptPayloads(t *testing.T) {
+	reader, err := decompressPayload(bytes.NewReader([]byte("[")), 0)
+	assert.Nil(t, err)
+	decompressed, _ := io.ReadAll(reader)
+	assert.Equal(t, string(decompressed), "[")
+
+	corrupt := gzipPayload(t, supplierADataset)
+	reader, err = decompressPayload(bytes.NewReader(corrupt[:len(corrupt)/2]), 0)
+	assert.Nil(t, err)
+	_, err = io.ReadAll(reader)
+	assert.Error(t, err)
+}
+
+func TestDecompressPayload_LimitsZstdDecoderMemory(t *testing.T) {
+	reader, err := decompressPayload(bytes.NewReader(zstdPayload(t, supplierAFeed(100))), 1024)
+	assert.Nil(t, err)
+	_, err = io.ReadAll(reader)
+	assert.ErrorIs(t, err, zstd.ErrDecoderSizeExceeded)
+	reader.Close()
+}
+
+func TestDirectDataLoaderService_WithCompressedHttpPayload(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Accept-Encoding"), SupplierAcceptEncoding)
+		w.Header().Set("Content-Encoding", "zstd")
+		w.WriteHeader(http.StatusOK)
+		w.Write(zstdPayload(t, supplierADataset))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(legacySuppliers(t, "supplierA:"+mockHttpServer.URL), repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 1)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{ValidHotelId})), 1)
+}
+
+func TestDirectDataLoaderService_WithGzipFileDump(t *testing.T) {
+	dir := t.TempDir()
+	writeSupplierDump(t, dir, "supplierB.json.gz", string(gzipPayload(t, supplierBDataset)))
+	repo := repository.NewInMemoryHotelRepository()
+	suppliers := []config.SupplierConfig{{Name: "supplierB", URL: "file://" + filepath.Join(dir, "*.gz")}}
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 1)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{ValidHotelId})), 1)
+}
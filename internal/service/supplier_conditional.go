@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+)
+
+// errNotModified is returned by readSupplierPayloads when the supplier
+// answered a conditional request with 304 Not Modified
+var errNotModified = errors.New("supplier payload not modified")
+
+// cacheValidators are the ETag and Last-Modified headers of the last payload
+// of a supplier, they are sent back to make the next request conditional
+type cacheValidators struct {
+	etag         string
+	lastModified string
+}
+
+func (v cacheValidators) isSet() bool {
+	return v.etag != "" || v.lastModified != ""
+}
+
+// responseValidators returns the validators of a supplier response
+func responseValidators(header http.Header) cacheValidators {
+	return cacheValidators{
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+	}
+}
+
+// conditionalHeader adds the If-None-Match and If-Modified-Since headers
+// matching the validators to a copy of the supplier headers
+func conditionalHeader(header http.Header, validators cacheValidators) http.Header {
+	header = header.Clone()
+	if validators.etag != "" {
+		header.Set("If-None-Match", validators.etag)
+	}
+	if validators.lastModified != "" {
+		header.Set("If-Modified-Since", validators.lastModified)
+	}
+	return header
+}
+
+func isConditionalRequest(header http.Header) bool {
+	return header.Get("If-None-Match") != "" || header.Get("If-Modified-Since") != ""
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const conditionalSupplierFeed = `[{"Id": "iJhz", "DestinationId": 5432, "Name": "Beach Villas"}]`
+
+func TestDirectDataLoaderService_UnchangedSupplierIsNotMergedAgain(t *testing.T) {
+	requests := 0
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			assert.Equal(t, r.Header.Get("If-None-Match"), `"v1"`)
+			assert.Equal(t, r.Header.Get("If-Modified-Since"), "Wed, 21 Oct 2026 07:28:00 GMT")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		assert.Equal(t, r.Header.Get("If-None-Match"), "")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2026 07:28:00 GMT")
+		w.Write([]byte(conditionalSupplierFeed))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL}}, repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+
+	// the catalog is not swapped when every supplier is unchanged
+	repo.InsertHotel(&model.Hotel{ID: "f8c9"})
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, requests, 2)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusUnchanged)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 1)
+	assert.Equal(t, report.HotelsLoaded, 2)
+	assert.Equal(t, len(repo.GetAllHotels()), 2)
+}
+
+func TestDirectDataLoaderService_UnchangedSupplierIsMergedWithChangedOnes(t *testing.T) {
+	unchangedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(conditionalSupplierFeed))
+	}))
+	defer unchangedServer.Close()
+	changedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("If-None-Match"), "")
+		w.Write([]byte(`[{"Id": "f8c9", "DestinationId": 5432, "Name": "Hotel Sentosa"}]`))
+	}))
+	defer changedServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{
+		{Name: "unchanged", Adapter: "supplierA", URL: unchangedServer.URL},
+		{Name: "changed", Adapter: "supplierA", URL: changedServer.URL},
+	}, repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusUnchanged)
+	assert.Equal(t, report.Suppliers[0].HotelsMerged, 1)
+	assert.Equal(t, report.Suppliers[1].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, report.HotelsLoaded, 2)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{"iJhz"})), 1)
+}
+
+func TestDirectDataLoaderService_ChangedSupplierConfigIsNotConditional(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("If-None-Match"), "")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(conditionalSupplierFeed))
+	}))
+	defer mockHttpServer.Close()
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL}}, repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	_, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+
+	loader.SetSuppliers([]config.SupplierConfig{{Name: "supplierA", URL: mockHttpServer.URL, Headers: map[string]string{"X-Api-Version": "2"}}})
+	report, err := loader.ReloadSuppliers(context.Background(), []string{"supplierA"})
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+}
+
+func TestSupplierHttpClient_UnconditionalNotModifiedIsAnError(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	_, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	var httpError *model.HttpError
+	assert.ErrorAs(t, err, &httpError)
+	assert.Equal(t, httpError.StatusCode, http.StatusNotModified)
+
+	resp, err := client.Get(context.Background(), mockHttpServer.URL, http.Header{"If-None-Match": {`"v1"`}})
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusNotModified)
+}
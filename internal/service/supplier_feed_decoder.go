@@ -0,0 +1,361 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// recordHandler is called with the raw bytes of every record of a feed, err is
+// set when the record itself is malformed and has to be skipped. Returning an
+// error stops the decoding of the feed
+type recordHandler func(raw []byte, err error) error
+
+// feedValues holds values read from a payload besides its records, keyed by
+// their path (e.g. the cursor of the next page)
+type feedValues map[string]json.RawMessage
+
+// feedDecoder reads every record of a supplier payload, an error is returned
+// when the payload as a whole cannot be read
+type feedDecoder func(r io.Reader, onRecord recordHandler) (feedValues, error)
+
+// recordBinder binds a raw record read by a feedDecoder to the supplier adapter
+type recordBinder func(adapter model.HotelLoaderData, raw []byte) (model.HotelLoaderData, error)
+
+// feedDecoderFor returns the decoder and the binder of the supplier feed
+// format, the format is validated with the supplier configuration
+func feedDecoderFor(supplier config.SupplierConfig) (feedDecoder, recordBinder) {
+	switch supplier.GetFormat() {
+	case config.FeedFormatNdjson:
+		return withoutValues(decodeNdjson), model.DecodeHotelLoaderData
+	case config.FeedFormatCsv:
+		return withoutValues(decodeCsv), model.DecodeHotelLoaderData
+	case config.FeedFormatXml:
+		return withoutValues(xmlFeedDecoder(supplier.RecordElement)), model.DecodeHotelLoaderDataXml
+	}
+	var valuePaths []string
+	if supplier.Pagination != nil && supplier.Pagination.GetType() == config.PaginationCursor {
+		valuePaths = append(valuePaths, supplier.Pagination.CursorPath)
+	}
+	if supplier.RecordsPath == "" && len(valuePaths) == 0 {
+		return withoutValues(decodeJsonArray), model.DecodeHotelLoaderData
+	}
+	return func(r io.Reader, onRecord recordHandler) (feedValues, error) {
+		return decodeJsonDocument(r, supplier.RecordsPath, valuePaths, onRecord)
+	}, model.DecodeHotelLoaderData
+}
+
+// withoutValues adapts the decoders of the formats that only carry records
+func withoutValues(decode func(r io.Reader, onRecord recordHandler) error) feedDecoder {
+	return func(r io.Reader, onRecord recordHandler) (feedValues, error) {
+		return nil, decode(r, onRecord)
+	}
+}
+
+// decodeJsonArray walks a JSON array token by token and calls onRecord with
+// the raw bytes of every element, so only a single record of the feed is held
+// in memory at a time instead of the whole decoded payload. A null feed is
+// treated as an empty array
+func decodeJsonArray(r io.Reader, onRecord recordHandler) error {
+	err := decodeJsonArrayElements(json.NewDecoder(r), onRecord)
+	if err != nil {
+		return &model.JsonError{Err: err}
+	}
+	return nil
+}
+
+func decodeJsonArrayElements(decoder *json.Decoder, onRecord recordHandler) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, got %v", token)
+	}
+	for decoder.More() {
+		var raw json.RawMessage
+		err = decoder.Decode(&raw)
+		if err != nil {
+			return err
+		}
+		err = onRecord(raw, nil)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+// decodeJsonDocument streams the records of the array found at the dot
+// separated recordsPath of a JSON document (the document itself when empty)
+// and returns the values found at valuePaths. Every other value is skipped
+// without being kept, only the objects leading to the paths are walked
+func decodeJsonDocument(r io.Reader, recordsPath string, valuePaths []string, onRecord recordHandler) (feedValues, error) {
+	walker := &jsonDocumentWalker{
+		decoder:     json.NewDecoder(r),
+		recordsPath: recordsPath,
+		valuePaths:  valuePaths,
+		values:      make(feedValues),
+		onRecord:    onRecord,
+	}
+	err := walker.walk("")
+	if err != nil {
+		return nil, &model.JsonError{Err: err}
+	}
+	if !walker.found {
+		return nil, &model.JsonError{Err: fmt.Errorf("records path %q not found", recordsPath)}
+	}
+	return walker.values, nil
+}
+
+type jsonDocumentWalker struct {
+	decoder     *json.Decoder
+	recordsPath string
+	valuePaths  []string
+	values      feedValues
+	onRecord    recordHandler
+	found       bool
+}
+
+func (w *jsonDocumentWalker) walk(path string) error {
+	if path == w.recordsPath {
+		w.found = true
+		return decodeJsonArrayElements(w.decoder, w.onRecord)
+	}
+	var raw json.RawMessage
+	for _, valuePath := range w.valuePaths {
+		if path == valuePath {
+			err := w.decoder.Decode(&raw)
+			w.values[path] = raw
+			return err
+		}
+	}
+	if !w.leadsToPath(path) {
+		return w.decoder.Decode(&raw)
+	}
+	token, err := w.decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	for w.decoder.More() {
+		// array elements are never on a path, they are skipped
+		if delim == '[' {
+			err = w.decoder.Decode(&raw)
+		} else {
+			var key json.Token
+			key, err = w.decoder.Token()
+			if err == nil {
+				err = w.walk(joinJsonPath(path, key.(string)))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = w.decoder.Token()
+	return err
+}
+
+// leadsToPath reports whether the value at path is a parent of the records
+// or of a requested value
+func (w *jsonDocumentWalker) leadsToPath(path string) bool {
+	for _, target := range append([]string{w.recordsPath}, w.valuePaths...) {
+		if path == "" || strings.HasPrefix(target, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func joinJsonPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// decodeNdjson reads newline delimited JSON, one record per line. Blank lines
+// are ignored and a malformed line is handed to onRecord with its error so the
+// rest of the feed can still be loaded
+func decodeNdjson(r io.Reader, onRecord recordHandler) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		raw, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 {
+			var recordErr error
+			if !json.Valid(raw) {
+				recordErr = &model.JsonError{Err: fmt.Errorf("malformed record on line %d", line)}
+			}
+			if err := onRecord(raw, recordErr); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// decodeCsv reads a CSV feed whose first row holds the column names, every
+// other row is handed to onRecord as a JSON object keyed by column name so
+// it can be bound by a mapping adapter like any JSON record. Empty cells are
+// left out of the record and a row that does not have as many cells as the
+// header is handed to onRecord with its error. A row that cannot be parsed is
+// handed over as its raw text, so the dead letter shows the rejected row
+func decodeCsv(r io.Reader, onRecord recordHandler) error {
+	rows := &csvRowRecorder{body: r}
+	reader := csv.NewReader(rows)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return &model.CsvError{Err: err}
+	}
+	// spreadsheet exports often start with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+	for {
+		start := reader.InputOffset()
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		end := reader.InputOffset()
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			raw := bytes.TrimRight(rows.row(start, end), "\r\n")
+			if err := onRecord(append([]byte(nil), raw...), &model.CsvError{Err: err}); err != nil {
+				return err
+			}
+			rows.discard(end)
+			continue
+		}
+		rows.discard(end)
+		if err != nil {
+			return &model.CsvError{Err: err}
+		}
+		record := make(map[string]string, len(header))
+		for i, value := range row {
+			if i < len(header) && value != "" {
+				record[header[i]] = value
+			}
+		}
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return &model.CsvError{Err: err}
+		}
+		var recordErr error
+		if len(row) != len(header) {
+			line, _ := reader.FieldPos(0)
+			recordErr = &model.CsvError{Err: fmt.Errorf("row on line %d has %d columns, expected %d", line, len(row), len(header))}
+		}
+		if err := onRecord(raw, recordErr); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRowRecorder keeps what the CSV reader read since the start of the
+// current row, so the raw text of a row that cannot be parsed can be
+// recovered from the input offsets of the reader
+type csvRowRecorder struct {
+	body   io.Reader
+	buf    []byte
+	offset int64
+}
+
+func (r *csvRowRecorder) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// row returns the bytes between two input offsets not discarded yet
+func (r *csvRowRecorder) row(start, end int64) []byte {
+	return r.buf[start-r.offset : end-r.offset]
+}
+
+// discard drops the bytes before the input offset end
+func (r *csvRowRecorder) discard(end int64) {
+	r.buf = append(r.buf[:0], r.buf[end-r.offset:]...)
+	r.offset = end
+}
+
+// xmlRecord captures an XML record element so it can be handed over verbatim
+type xmlRecord struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   []byte     `xml:",innerxml"`
+}
+
+// xmlFeedDecoder returns a decoder streaming the elements named recordElement
+// of an XML feed, or every child element of the document root when
+// recordElement is empty. A feed that is not well-formed fails as a whole
+// since the XML decoder cannot resynchronize after a syntax error, records
+// the adapter cannot bind are rejected one by one like JSON records
+func xmlFeedDecoder(recordElement string) func(r io.Reader, onRecord recordHandler) error {
+	return func(r io.Reader, onRecord recordHandler) error {
+		decoder := xml.NewDecoder(r)
+		depth := 0
+		hasRoot := false
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF && !hasRoot {
+				return &model.XmlError{Err: errors.New("no root element")}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return &model.XmlError{Err: err}
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				hasRoot = true
+				isRecord := depth == 1
+				if recordElement != "" {
+					isRecord = t.Name.Local == recordElement
+				}
+				if !isRecord {
+					depth++
+					continue
+				}
+				var record xmlRecord
+				err = decoder.DecodeElement(&record, &t)
+				if err != nil {
+					return &model.XmlError{Err: err}
+				}
+				raw, err := xml.Marshal(record)
+				if err != nil {
+					return &model.XmlError{Err: err}
+				}
+				if err := onRecord(raw, nil); err != nil {
+					return err
+				}
+			case xml.EndElement:
+				depth--
+			}
+		}
+	}
+}
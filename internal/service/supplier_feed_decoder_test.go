@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"datamerge/internal/model"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJsonArray(t *testing.T) {
+	var records []string
+	err := decodeJsonArray(strings.NewReader(`[{"id": 1}, 2, "three", null]`), func(raw []byte, err error) error {
+		assert.Nil(t, err)
+		records = append(records, string(raw))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, records, []string{`{"id": 1}`, "2", `"three"`, "null"})
+}
+
+func TestDecodeJsonArray_NullAndEmptyFeeds(t *testing.T) {
+	for _, feed := range []string{"null", "[]"} {
+		called := false
+		err := decodeJsonArray(strings.NewReader(feed), func(raw []byte, err error) error {
+			called = true
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.False(t, called)
+	}
+}
+
+func TestDecodeJsonArray_InvalidFeeds(t *testing.T) {
+	for _, feed := range []string{"", "{}", "<html></html>", `[{"id": 1}, {"id": `, `[{"id": 1}`} {
+		err := decodeJsonArray(strings.NewReader(feed), func(raw []byte, err error) error { return nil })
+		var jsonError *model.JsonError
+		assert.ErrorAs(t, err, &jsonError, feed)
+	}
+}
+
+func TestDecodeNdjson_SkipsMalformedLines(t *testing.T) {
+	feed := "{\"id\": 1}\n\n  {\"id\": 2\n{\"id\": 3}\r\n{\"id\": 4}"
+	var records []string
+	var malformed []string
+	err := decodeNdjson(strings.NewReader(feed), func(raw []byte, err error) error {
+		if err != nil {
+			malformed = append(malformed, err.Error())
+			return nil
+		}
+		records = append(records, string(raw))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, records, []string{`{"id": 1}`, `{"id": 3}`, `{"id": 4}`})
+	assert.Equal(t, len(malformed), 1)
+	assert.Contains(t, malformed[0], "line 3")
+}
+
+func TestDecodeCsv(t *testing.T) {
+	feed := "\uFEFFid,name,amenities\n" +
+		"iJhz,Beach Villas,pool|wifi\n" +
+		"f8c9,\"Hotel \"\"Quoted\"\", Singapore\",\n" +
+		"SjyX,InterContinental\n" +
+		"bad,\"unterminated\"quote,\n" +
+		"ypVe,Mandarin,\n"
+	var records []string
+	var malformed []string
+	var rejected []string
+	err := decodeCsv(strings.NewReader(feed), func(raw []byte, err error) error {
+		if err != nil {
+			malformed = append(malformed, err.Error())
+			rejected = append(rejected, string(raw))
+			return nil
+		}
+		records = append(records, string(raw))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, records, []string{
+		`{"amenities":"pool|wifi","id":"iJhz","name":"Beach Villas"}`,
+		`{"id":"f8c9","name":"Hotel \"Quoted\", Singapore"}`,
+		`{"id":"ypVe","name":"Mandarin"}`,
+	})
+	assert.Equal(t, len(malformed), 2)
+	assert.Contains(t, malformed[0], "line 4 has 2 columns, expected 3")
+	assert.Contains(t, malformed[1], "parse error on line 5")
+	assert.Equal(t, rejected[1], `bad,"unterminated"quote,`)
+}
+
+func TestDecodeCsv_EmptyFeed(t *testing.T) {
+	called := false
+	err := decodeCsv(strings.NewReader(""), func(raw []byte, err error) error {
+		called = true
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.False(t, called)
+}
+
+func TestXmlFeedDecoder(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+	<Response>
+		<Hotel code="iJhz"><Name>Beach Villas</Name></Hotel>
+		<Hotel code="f8c9"/>
+	</Response>`
+	var records []string
+	err := xmlFeedDecoder("")(strings.NewReader(feed), func(raw []byte, err error) error {
+		assert.Nil(t, err)
+		records = append(records, string(raw))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, records, []string{`<Hotel code="iJhz"><Name>Beach Villas</Name></Hotel>`, `<Hotel code="f8c9"></Hotel>`})
+}
+
+func TestXmlFeedDecoder_WithRecordElement(t *testing.T) {
+	feed := `<Response><Status>OK</Status><Hotels><Hotel code="iJhz"/><Hotel code="f8c9"/></Hotels></Response>`
+	var records []string
+	err := xmlFeedDecoder("Hotel")(strings.NewReader(feed), func(raw []byte, err error) error {
+		records = append(records, string(raw))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, records, []string{`<Hotel code="iJhz"></Hotel>`, `<Hotel code="f8c9"></Hotel>`})
+}
+
+func TestXmlFeedDecoder_InvalidFeeds(t *testing.T) {
+	for _, feed := range []string{`<Response><Hotel code="iJhz"></Response>`, `[{"id": 1}]`, `<Response><Hotel>`} {
+		err := xmlFeedDecoder("")(strings.NewReader(feed), func(raw []byte, err error) error { return nil })
+		var xmlError *model.XmlError
+		assert.ErrorAs(t, err, &xmlError, feed)
+	}
+}
+
+// supplierBFeedReader generates a supplierB feed with the given number of
+// records as it is read, so the feed itself is never held in memory
+type supplierBFeedReader struct {
+	records int
+	next    int
+	buf     bytes.Buffer
+}
+
+func (r *supplierBFeedReader) Read(p []byte) (int, error) {
+	for r.buf.Len() < len(p) && r.next <= r.records {
+		switch {
+		case r.next == r.records:
+			r.buf.WriteString("]")
+		case r.next == 0:
+			r.buf.WriteString("[")
+		default:
+			r.buf.WriteString(",")
+		}
+		if r.next < r.records {
+			i := r.next
+			fmt.Fprintf(&r.buf, `{"hotel_id": "hotel%d", "destination_id": %d, "hotel_name": "Hotel %d",
+			"location": {"address": "8 Sentosa Gateway", "country": "Singapore"},
+			"details": "Surrounded by tropical gardens, these upscale villas in elegant Colonial-style buildings.",
+			"amenities": {"general": ["outdoor pool", "business center", "childcare"], "room": ["tv", "coffee machine"]},
+			"images": {"rooms": [{"link": "https://example.com/rooms/%d.jpg", "caption": "Double room"}], "site": []},
+			"booking_conditions": ["All children are welcome."]}`, i, i, i, i)
+		}
+		r.next++
+	}
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+// peakLiveHeap streams a feed of the given number of records, binding every
+// record and dropping it, and returns how much the live heap grew at most
+// The live heap is sampled after a garbage collection every 100 records
+func peakLiveHeap(t *testing.T, records int) uint64 {
+	adapter := &model.HotelDataLoaderSupplierB{}
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline, peak := stats.HeapAlloc, stats.HeapAlloc
+	decoded := 0
+	err := decodeJsonArray(&supplierBFeedReader{records: records}, func(raw []byte, err error) error {
+		_, err = model.DecodeHotelLoaderData(adapter, raw)
+		if err != nil {
+			return err
+		}
+		decoded++
+		if decoded%100 == 0 {
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, decoded, records)
+	return peak - baseline
+}
+
+// TestDecodeJsonArray_KeepsLiveHeapBounded checks that streaming a feed keeps
+// a single record alive at a time: the live heap does not grow with the feed,
+// a 100 times larger feed (about 50MB) needs about the same memory
+func TestDecodeJsonArray_KeepsLiveHeapBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("collects the garbage every 100 records")
+	}
+	small := peakLiveHeap(t, 1000)
+	large := peakLiveHeap(t, 100000)
+	assert.Less(t, large, uint64(1<<20))
+	assert.LessOrEqual(t, large, small+256<<10)
+}
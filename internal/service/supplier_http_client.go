@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/utils"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultSupplierTimeout        = 30 * time.Second
+	DefaultSupplierMaxRetries     = 3
+	DefaultSupplierInitialBackoff = 500 * time.Millisecond
+	DefaultSupplierMaxBackoff     = 10 * time.Second
+)
+
+// SupplierHttpClientConfig controls how a supplier endpoint is called
+// Timeout is applied to every attempt (including reading the body),
+// MaxRetries is the number of extra attempts made for transient failures
+// and the wait between attempts doubles from InitialBackoff up to MaxBackoff
+// Auth, when set, authenticates every attempt and URLPolicy restricts the
+// urls, redirects and addresses the client connects to. TLS, when set, is the
+// TLS configuration of the connections of the client
+type SupplierHttpClientConfig struct {
+	Timeout        time.Duration
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Auth           *config.SupplierAuthConfig
+	URLPolicy      config.SupplierURLPolicy
+	TLS            *tls.Config
+}
+
+func DefaultSupplierHttpClientConfig() SupplierHttpClientConfig {
+	return SupplierHttpClientConfig{
+		Timeout:        DefaultSupplierTimeout,
+		MaxRetries:     DefaultSupplierMaxRetries,
+		InitialBackoff: DefaultSupplierInitialBackoff,
+		MaxBackoff:     DefaultSupplierMaxBackoff,
+	}
+}
+
+// SupplierHttpClient fetches supplier payloads over HTTP. Transient failures
+// (network errors, 408, 429 and 5xx responses) are retried with an exponential
+// backoff, every other non-2xx response fails straight away with a HttpError
+// carrying the status code. Cancelling the context aborts the request and any
+// pending backoff. Every attempt waits for the rate limiter of the supplier
+type SupplierHttpClient struct {
+	config  SupplierHttpClientConfig
+	client  *http.Client
+	limiter *rateLimiter
+	// transport is only set when the client does not use a shared transport
+	transport *http.Transport
+}
+
+func NewSupplierHttpClient(config SupplierHttpClientConfig) *SupplierHttpClient {
+	client := &http.Client{
+		Timeout:       config.Timeout,
+		CheckRedirect: checkRedirect(config.URLPolicy, config.Auth),
+	}
+	if config.URLPolicy.BlockPrivateNetworks {
+		client.Transport = privateNetworkGuard()
+	}
+	var transport *http.Transport
+	if config.TLS != nil {
+		// connections with their own TLS configuration cannot be shared
+		base := http.DefaultTransport.(*http.Transport)
+		if config.URLPolicy.BlockPrivateNetworks {
+			base = privateNetworkGuard()
+		}
+		transport = base.Clone()
+		transport.TLSClientConfig = config.TLS
+		client.Transport = transport
+	}
+	return &SupplierHttpClient{
+		config:    config,
+		client:    client,
+		transport: transport,
+	}
+}
+
+// Close releases the idle connections of a client with its own transport
+func (c *SupplierHttpClient) Close() {
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+}
+
+// Get returns the first successful (2xx) response for the url, or the 304 Not
+// Modified response of a conditional request. The caller is responsible for
+// closing the response body
+func (c *SupplierHttpClient) Get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, &model.HttpError{URL: utils.RedactURL(url), Err: err}
+		}
+		resp, retryAfter, err := c.do(ctx, url, header)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.config.MaxRetries || !isRetryable(ctx, err) {
+			return nil, err
+		}
+		if err := sleepWithContext(ctx, c.backoff(attempt, retryAfter)); err != nil {
+			return nil, &model.HttpError{URL: utils.RedactURL(url), Err: err}
+		}
+	}
+}
+
+// do performs a single attempt, the returned duration is the Retry-After
+// hint sent along with a failed response (if any)
+func (c *SupplierHttpClient) do(ctx context.Context, rawURL string, header http.Header) (*http.Response, time.Duration, *model.HttpError) {
+	redactedURL := utils.RedactURL(rawURL)
+	if err := c.config.URLPolicy.CheckURL(rawURL); err != nil {
+		return nil, 0, &model.HttpError{URL: redactedURL, Err: &model.URLPolicyError{Err: err}}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, &model.HttpError{URL: redactedURL, Err: redactURLError(err)}
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", SupplierAcceptEncoding)
+	}
+	secrets, err := authenticateRequest(req, c.config.Auth, time.Now())
+	if err != nil {
+		return nil, 0, &model.HttpError{URL: redactedURL, Err: err}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// the url of the transport error is the authenticated one
+		return nil, 0, &model.HttpError{URL: redactedURL, Err: redactURLError(err, secrets...)}
+	}
+	if resp.StatusCode == http.StatusNotModified && isConditionalRequest(req.Header) {
+		return resp, 0, nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		// drain the body so the underlying connection can be reused
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, retryAfter, &model.HttpError{URL: redactedURL, StatusCode: resp.StatusCode}
+	}
+	return resp, 0, nil
+}
+
+// redactURLError redacts the url quoted in the message of a url.Error
+func redactURLError(err error, secrets ...string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = utils.RedactURL(urlErr.URL, secrets...)
+	}
+	return err
+}
+
+// backoff doubles the wait on every attempt, a Retry-After hint sent by
+// the supplier takes precedence but is still capped by MaxBackoff
+func (c *SupplierHttpClient) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	wait := c.config.InitialBackoff << uint(attempt)
+	if retryAfter > 0 {
+		wait = retryAfter
+	}
+	if c.config.MaxBackoff > 0 && (wait > c.config.MaxBackoff || wait < 0) {
+		wait = c.config.MaxBackoff
+	}
+	return wait
+}
+
+func isRetryable(ctx context.Context, err *model.HttpError) bool {
+	var policyErr *model.URLPolicyError
+	if ctx.Err() != nil || errors.As(err, &policyErr) {
+		return false
+	}
+	switch err.StatusCode {
+	case 0:
+		// url.Error implements net.Error itself, only the error it wraps
+		// tells us whether it was a transport failure or e.g. a bad url
+		cause := err.Err
+		var urlErr *url.Error
+		if errors.As(cause, &urlErr) {
+			cause = urlErr.Err
+		}
+		var netErr net.Error
+		return errors.As(cause, &netErr) || errors.Is(cause, io.EOF) || errors.Is(cause, io.ErrUnexpectedEOF)
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/model"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testClientConfig = SupplierHttpClientConfig{
+	Timeout:        time.Second,
+	MaxRetries:     2,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+func TestSupplierHttpClient_RetriesTransientFailures(t *testing.T) {
+	var calls int32
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[]"))
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(testClientConfig)
+	resp, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, string(body), "[]")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(3))
+}
+
+func TestSupplierHttpClient_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls int32
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(testClientConfig)
+	_, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	var httpErr *model.HttpError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, httpErr.StatusCode, http.StatusBadGateway)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(testClientConfig.MaxRetries+1))
+}
+
+func TestSupplierHttpClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<html>not found</html>"))
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(testClientConfig)
+	_, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	var httpErr *model.HttpError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, httpErr.StatusCode, http.StatusNotFound)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1))
+}
+
+func TestSupplierHttpClient_SendsHeaders(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("X-Api-Key"), "secret")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(testClientConfig)
+	resp, err := client.Get(context.Background(), mockHttpServer.URL, http.Header{"X-Api-Key": []string{"secret"}})
+	assert.Nil(t, err)
+	resp.Body.Close()
+}
+
+func TestSupplierHttpClient_TimeoutIsApplied(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{Timeout: 10 * time.Millisecond})
+	_, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	assert.Error(t, err)
+	assert.IsType(t, err, &model.HttpError{})
+}
+
+func TestSupplierHttpClient_ContextCancellationAbortsRetries(t *testing.T) {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{
+		Timeout:        time.Second,
+		MaxRetries:     10,
+		InitialBackoff: time.Second,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	startedAt := time.Now()
+	_, err := client.Get(ctx, mockHttpServer.URL, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(startedAt), time.Second)
+}
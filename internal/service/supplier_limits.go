@@ -0,0 +1,127 @@
+package service
+
+import (
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"io"
+)
+
+const (
+	DefaultSupplierMaxPayloadBytes = 256 << 20
+	DefaultSupplierMaxRecords      = 1000000
+	DefaultSupplierMaxRecordBytes  = 1 << 20
+)
+
+// SupplierPayloadLimits bounds what a single supplier fetch reads, so a runaway
+// feed is aborted instead of exhausting the memory of the service
+// MaxPayloadBytes is the size of every payload of the supplier together (once
+// decompressed), MaxRecords the number of records and MaxRecordBytes the size
+// of a single record. A zero limit is unlimited
+type SupplierPayloadLimits struct {
+	MaxPayloadBytes int64
+	MaxRecords      int
+	MaxRecordBytes  int64
+}
+
+func DefaultSupplierPayloadLimits() SupplierPayloadLimits {
+	return SupplierPayloadLimits{
+		MaxPayloadBytes: DefaultSupplierMaxPayloadBytes,
+		MaxRecords:      DefaultSupplierMaxRecords,
+		MaxRecordBytes:  DefaultSupplierMaxRecordBytes,
+	}
+}
+
+// forSupplier returns the limits with the ones set in the supplier config
+func (l SupplierPayloadLimits) forSupplier(supplier config.SupplierConfig) SupplierPayloadLimits {
+	if supplier.MaxPayloadBytes > 0 {
+		l.MaxPayloadBytes = supplier.MaxPayloadBytes
+	}
+	if supplier.MaxRecords > 0 {
+		l.MaxRecords = supplier.MaxRecords
+	}
+	if supplier.MaxRecordBytes > 0 {
+		l.MaxRecordBytes = supplier.MaxRecordBytes
+	}
+	return l
+}
+
+// payloadGuard enforces the limits over every payload of a supplier fetch, err
+// is the LimitError of the first exceeded limit
+// checkRecord checks the exact size of a record once it is read, the decoders
+// buffer a whole record first so the reader bounds what is read between two
+// records as well: at most twice MaxRecordBytes, which leaves room for the
+// separators and the read ahead of the decoders
+type payloadGuard struct {
+	limits      SupplierPayloadLimits
+	bytesRead   int64
+	recordBytes int64
+	records     int
+	err         error
+}
+
+func newPayloadGuard(limits SupplierPayloadLimits) *payloadGuard {
+	return &payloadGuard{limits: limits}
+}
+
+// reader returns body failing with the LimitError once the payloads read
+// through the guard exceed MaxPayloadBytes, or once more than twice
+// MaxRecordBytes are read without a record being completed
+func (g *payloadGuard) reader(body io.Reader) io.Reader {
+	g.recordBytes = 0
+	return &guardedReader{guard: g, body: body}
+}
+
+// checkRecord counts a record of the feed and checks it against the record limits
+func (g *payloadGuard) checkRecord(raw []byte) error {
+	g.records++
+	if g.limits.MaxRecords > 0 && g.records > g.limits.MaxRecords {
+		return g.exceeded("max_records", int64(g.limits.MaxRecords))
+	}
+	if g.limits.MaxRecordBytes > 0 && int64(len(raw)) > g.limits.MaxRecordBytes {
+		return g.exceeded("max_record_bytes", g.limits.MaxRecordBytes)
+	}
+	g.recordBytes = 0
+	return nil
+}
+
+func (g *payloadGuard) exceeded(limit string, max int64) error {
+	if g.err == nil {
+		g.err = &model.LimitError{Limit: limit, Max: max}
+	}
+	return g.err
+}
+
+type guardedReader struct {
+	guard *payloadGuard
+	body  io.Reader
+}
+
+func (r *guardedReader) Read(p []byte) (int, error) {
+	g := r.guard
+	if g.err != nil {
+		return 0, g.err
+	}
+	max := g.limits.MaxPayloadBytes
+	// never read more than a byte past the limit
+	if remaining := max - g.bytesRead + 1; max > 0 && int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	// the bytes read between two records may belong to the next record, the
+	// record limit is only exceeded when a decoder asks for more past it
+	if maxRecord := 2 * g.limits.MaxRecordBytes; maxRecord > 0 {
+		remaining := maxRecord - g.recordBytes
+		if remaining <= 0 {
+			return 0, g.exceeded("max_record_bytes", g.limits.MaxRecordBytes)
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err := r.body.Read(p)
+	g.bytesRead += int64(n)
+	g.recordBytes += int64(n)
+	if max > 0 && g.bytesRead > max {
+		return 0, g.exceeded("max_payload_bytes", max)
+	}
+	return n, err
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// supplierAFeed builds a supplierA feed with the given number of records
+func supplierAFeed(records int) string {
+	var feed []string
+	for i := 0; i < records; i++ {
+		feed = append(feed, fmt.Sprintf(`{"Id": "hotel%d", "DestinationId": 5432, "Name": "Hotel %d"}`, i, i))
+	}
+	return "[" + strings.Join(feed, ",") + "]"
+}
+
+func loadLimitedSupplier(t *testing.T, payload []byte, supplier config.SupplierConfig, limits SupplierPayloadLimits) *model.LoadReport {
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+	t.Cleanup(mockHttpServer.Close)
+	supplier.Name = "supplierA"
+	supplier.URL = mockHttpServer.URL
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{supplier}, repository.NewInMemoryHotelRepository(), logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	loader.SetPayloadLimits(limits)
+	report, _ := loader.LoadData(context.Background())
+	return report
+}
+
+func assertLimitExceeded(t *testing.T, report *model.LoadReport, limit string) {
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+	var limitErr *model.LimitError
+	assert.ErrorAs(t, report.Suppliers[0].Err, &limitErr)
+	if limitErr != nil {
+		assert.Equal(t, limitErr.Limit, limit)
+	}
+	assert.Equal(t, report.HotelsLoaded, 0)
+}
+
+func TestDirectDataLoaderService_AbortsPayloadOverMaxBytes(t *testing.T) {
+	feed := supplierAFeed(100)
+	report := loadLimitedSupplier(t, []byte(feed), config.SupplierConfig{}, SupplierPayloadLimits{MaxPayloadBytes: int64(len(feed) - 1)})
+	assertLimitExceeded(t, report, "max_payload_bytes")
+	assert.Equal(t, report.Suppliers[0].Error, fmt.Sprintf("supplier exceeds its max_payload_bytes limit of %d", len(feed)-1))
+
+	report = loadLimitedSupplier(t, []byte(feed), config.SupplierConfig{}, SupplierPayloadLimits{MaxPayloadBytes: int64(len(feed))})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+}
+
+func TestDirectDataLoaderService_LimitsDecompressedPayload(t *testing.T) {
+	feed := supplierAFeed(1000)
+	report := loadLimitedSupplier(t, gzipPayload(t, feed), config.SupplierConfig{}, SupplierPayloadLimits{MaxPayloadBytes: 4096})
+	assertLimitExceeded(t, report, "max_payload_bytes")
+}
+
+func TestDirectDataLoaderService_AbortsFeedOverMaxRecords(t *testing.T) {
+	report := loadLimitedSupplier(t, []byte(supplierAFeed(10)), config.SupplierConfig{}, SupplierPayloadLimits{MaxRecords: 5})
+	assertLimitExceeded(t, report, "max_records")
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 5)
+}
+
+func TestDirectDataLoaderService_AbortsRecordOverMaxRecordBytes(t *testing.T) {
+	feed := `[{"Id": "iJhz", "Name": "Beach Villas"}, {"Id": "f8c9", "Name": "` + strings.Repeat("x", 100) + `"}]`
+	report := loadLimitedSupplier(t, []byte(feed), config.SupplierConfig{Format: config.FeedFormatJson}, SupplierPayloadLimits{MaxRecordBytes: 64})
+	assertLimitExceeded(t, report, "max_record_bytes")
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 1)
+}
+
+func TestDirectDataLoaderService_SupplierOverridesLimits(t *testing.T) {
+	report := loadLimitedSupplier(t, []byte(supplierAFeed(10)), config.SupplierConfig{MaxRecords: 20}, SupplierPayloadLimits{MaxRecords: 5})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	report = loadLimitedSupplier(t, []byte(supplierAFeed(10)), config.SupplierConfig{MaxRecords: 2}, DefaultSupplierPayloadLimits())
+	assertLimitExceeded(t, report, "max_records")
+}
+
+func TestDirectDataLoaderService_LimitsApplyToEveryFormat(t *testing.T) {
+	ndjson := strings.Repeat(`{"Id": "iJhz", "Name": "Beach Villas"}`+"\n", 10)
+	report := loadLimitedSupplier(t, []byte(ndjson), config.SupplierConfig{Format: config.FeedFormatNdjson}, SupplierPayloadLimits{MaxRecords: 5})
+	assertLimitExceeded(t, report, "max_records")
+	csv := "Id,Name\n" + strings.Repeat("iJhz,Beach Villas\n", 10)
+	report = loadLimitedSupplier(t, []byte(csv), config.SupplierConfig{Format: config.FeedFormatCsv}, SupplierPayloadLimits{MaxPayloadBytes: 64})
+	assertLimitExceeded(t, report, "max_payload_bytes")
+}
+
+func TestGuardedReader_NeverReadsPastTheLimit(t *testing.T) {
+	guard := newPayloadGuard(SupplierPayloadLimits{MaxPayloadBytes: 10})
+	body := strings.NewReader(strings.Repeat("x", 100))
+	read, err := io.ReadAll(guard.reader(body))
+	assert.True(t, errors.Is(err, guard.err))
+	assert.Equal(t, len(read), 0)
+	assert.Equal(t, body.Len(), 89)
+}
+
+// endlessReader serves an endless record, it counts the bytes it served
+type endlessReader struct {
+	prefix string
+	served int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	n := copy(p, strings.Repeat("x", len(p)))
+	if r.served < len(r.prefix) {
+		n = copy(p, r.prefix[r.served:])
+	}
+	r.served += n
+	return n, nil
+}
+
+func TestPayloadGuard_BoundsRecordWhileReading(t *testing.T) {
+	decoders := map[string]feedDecoder{
+		`[{"Id": "`:       withoutValues(decodeJsonArray),
+		`{"Id": "`:        withoutValues(decodeNdjson),
+		"Id\n":            withoutValues(decodeCsv),
+		"<hotels><hotel>": withoutValues(xmlFeedDecoder("")),
+	}
+	for prefix, decode := range decoders {
+		guard := newPayloadGuard(SupplierPayloadLimits{MaxRecordBytes: 1024})
+		body := &endlessReader{prefix: prefix}
+		_, err := decode(guard.reader(body), func(raw []byte, err error) error {
+			return guard.checkRecord(raw)
+		})
+		assert.Error(t, err, prefix)
+		var limitErr *model.LimitError
+		assert.ErrorAs(t, guard.err, &limitErr, prefix)
+		assert.Equal(t, limitErr.Limit, "max_record_bytes", prefix)
+		assert.LessOrEqual(t, body.served, 2*1024+1, prefix)
+	}
+}
+
+func TestPayloadGuard_ReadsRecordsUpToMaxRecordBytes(t *testing.T) {
+	record := `{"Id": "iJhz", "Name": "` + strings.Repeat("x", 1000) + `"}`
+	ndjson := strings.Repeat(record+"\n", 20)
+	report := loadLimitedSupplier(t, []byte(ndjson), config.SupplierConfig{Format: config.FeedFormatNdjson}, SupplierPayloadLimits{MaxRecordBytes: int64(len(record))})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 20)
+	report = loadLimitedSupplier(t, []byte(supplierAFeed(20)), config.SupplierConfig{}, SupplierPayloadLimits{MaxRecordBytes: 64})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"datamerge/internal/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// supplierPaginator computes the url of every page of a paginated supplier
+type supplierPaginator struct {
+	config   *config.SupplierPaginationConfig
+	position int
+}
+
+func newSupplierPaginator(pagination *config.SupplierPaginationConfig) *supplierPaginator {
+	return &supplierPaginator{config: pagination, position: pagination.GetStart()}
+}
+
+// first returns the url of the first page
+func (p *supplierPaginator) first(rawURL string) (string, error) {
+	switch p.config.GetType() {
+	case config.PaginationPage, config.PaginationOffset:
+		return p.withQuery(rawURL, p.config.GetParam(), strconv.Itoa(p.position))
+	}
+	return p.withQuery(rawURL, "", "")
+}
+
+// next returns the url of the page following the page at current, ok is false
+// once every page was fetched. page is what the decoding of the current page
+// returned and header its response header
+func (p *supplierPaginator) next(current string, page payloadResult, header http.Header) (next string, ok bool, err error) {
+	switch p.config.GetType() {
+	case config.PaginationPage, config.PaginationOffset:
+		if page.records == 0 {
+			return "", false, nil
+		}
+		if p.config.GetType() == config.PaginationPage {
+			p.position++
+		} else {
+			p.position += page.records
+		}
+		next, err = p.withQuery(current, p.config.GetParam(), strconv.Itoa(p.position))
+		return next, err == nil, err
+	case config.PaginationCursor:
+		cursor, err := cursorValue(page.values[p.config.CursorPath])
+		if err != nil || cursor == "" {
+			return "", false, err
+		}
+		next, err = p.withQuery(current, p.config.GetParam(), cursor)
+		return next, err == nil, err
+	case config.PaginationLink:
+		link := nextLink(header)
+		if link == "" {
+			return "", false, nil
+		}
+		base, err := url.Parse(current)
+		if err != nil {
+			return "", false, err
+		}
+		resolved, err := base.Parse(link)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid next link %q: %w", link, err)
+		}
+		// the supplier auth is applied to every page, a next link cannot
+		// send it to another origin
+		if !strings.EqualFold(resolved.Scheme, base.Scheme) || !strings.EqualFold(resolved.Host, base.Host) {
+			return "", false, fmt.Errorf("next link %s://%s is not on the supplier origin", resolved.Scheme, resolved.Host)
+		}
+		return resolved.String(), true, nil
+	}
+	return "", false, fmt.Errorf("pagination type %q is not supported", p.config.Type)
+}
+
+// withQuery sets the query parameter name (if any) and the page size of the url
+func (p *supplierPaginator) withQuery(rawURL, name, value string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	if name != "" {
+		query.Set(name, value)
+	}
+	if p.config.Size > 0 {
+		query.Set(p.config.SizeParam, strconv.Itoa(p.config.Size))
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// cursorValue returns the cursor of the next page, a missing or null cursor
+// (or an empty string) means there is no next page
+func cursorValue(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var cursor interface{}
+	err := json.Unmarshal(raw, &cursor)
+	if err != nil {
+		return "", err
+	}
+	switch v := cursor.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("cursor %s is neither a string nor a number", string(raw))
+}
+
+// nextLink returns the url of the rel="next" link of the Link headers
+// e.g. Link: <https://api.supplier.com/hotels?page=2>; rel="next"
+func nextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "rel") {
+					for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+						if strings.EqualFold(rel, "next") {
+							return target[1 : len(target)-1]
+						}
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
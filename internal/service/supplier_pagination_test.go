@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJsonDocument(t *testing.T) {
+	document := `{
+		"meta": {"total": 2, "pages": [1, 2], "next": {"cursor": "c2"}},
+		"skipped": [{"result": {"hotels": [{"id": "ignored"}]}}],
+		"result": {"count": 2, "hotels": [{"id": 1}, {"id": 2}]}
+	}`
+	var records []string
+	values, err := decodeJsonDocument(strings.NewReader(document), "result.hotels", []string{"meta.next.cursor", "meta.missing"}, func(raw []byte, err error) error {
+		records = append(records, string(raw))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, records, []string{`{"id": 1}`, `{"id": 2}`})
+	assert.Equal(t, string(values["meta.next.cursor"]), `"c2"`)
+	_, present := values["meta.missing"]
+	assert.False(t, present)
+
+	_, err = decodeJsonDocument(strings.NewReader(`{"data": {"hotels": []}}`), "result.hotels", nil, func(raw []byte, err error) error { return nil })
+	var jsonError *model.JsonError
+	assert.ErrorAs(t, err, &jsonError)
+	_, err = decodeJsonDocument(strings.NewReader(`{"result": {"hotels": {}}}`), "result.hotels", nil, func(raw []byte, err error) error { return nil })
+	assert.ErrorAs(t, err, &jsonError)
+}
+
+func TestNextLink(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `<https://api.supplier.com/hotels?page=1>; rel="prev first", <https://api.supplier.com/hotels?page=3>; rel="next"`)
+	assert.Equal(t, nextLink(header), "https://api.supplier.com/hotels?page=3")
+	header.Set("Link", `</hotels?page=1>; rel="first"`)
+	assert.Equal(t, nextLink(header), "")
+}
+
+// hotelPages serves supplierA records, pageRecords[i] being the ids of page i+1
+func hotelPages(pageRecords [][]string) func(page int) string {
+	return func(page int) string {
+		if page < 1 || page > len(pageRecords) {
+			return "[]"
+		}
+		var records []string
+		for _, id := range pageRecords[page-1] {
+			records = append(records, fmt.Sprintf(`{"Id": %q, "DestinationId": 5432, "Name": "Hotel %s"}`, id, id))
+		}
+		return "[" + strings.Join(records, ",") + "]"
+	}
+}
+
+func loadPaginatedSupplier(t *testing.T, url string, supplier config.SupplierConfig) (*model.LoadReport, *repository.InMemoryHotelRepository, error) {
+	supplier.Name = "supplierA"
+	supplier.URL = url
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{supplier}, repo, logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	report, err := loader.LoadData(context.Background())
+	return report, repo, err
+}
+
+func TestDirectDataLoaderService_WithPagePagination(t *testing.T) {
+	pages := hotelPages([][]string{{"iJhz", "f8c9"}, {"SjyX"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Query().Get("per_page"), "2")
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		w.Write([]byte(pages(page)))
+	}))
+	defer mockHttpServer.Close()
+	report, repo, err := loadPaginatedSupplier(t, mockHttpServer.URL+"/hotels?lang=en", config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationPage, Param: "p", SizeParam: "per_page", Size: 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 3)
+	assert.Equal(t, len(report.Suppliers[0].Pages), 3)
+	assert.Equal(t, report.Suppliers[0].Pages[1], model.SupplierPageReport{
+		Page:            2,
+		URL:             mockHttpServer.URL + "/hotels?lang=en&p=2&per_page=2",
+		RecordsReceived: 1,
+	})
+	assert.Equal(t, len(repo.GetAllHotels()), 3)
+}
+
+func TestDirectDataLoaderService_WithOffsetPagination(t *testing.T) {
+	pages := hotelPages([][]string{{"iJhz", "f8c9"}, {"SjyX"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("offset") {
+		case "0":
+			w.Write([]byte(pages(1)))
+		case "2":
+			w.Write([]byte(pages(2)))
+		default:
+			w.Write([]byte(pages(0)))
+		}
+	}))
+	defer mockHttpServer.Close()
+	report, repo, err := loadPaginatedSupplier(t, mockHttpServer.URL, config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationOffset},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Suppliers[0].Pages), 3)
+	assert.Equal(t, len(repo.GetAllHotels()), 3)
+}
+
+func TestDirectDataLoaderService_WithCursorPagination(t *testing.T) {
+	pages := hotelPages([][]string{{"iJhz"}, {"f8c9"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "" {
+			fmt.Fprintf(w, `{"data": %s, "paging": {"next": "abc=="}}`, pages(1))
+			return
+		}
+		assert.Equal(t, r.URL.Query().Get("cursor"), "abc==")
+		fmt.Fprintf(w, `{"data": %s, "paging": {"next": null}}`, pages(2))
+	}))
+	defer mockHttpServer.Close()
+	report, repo, err := loadPaginatedSupplier(t, mockHttpServer.URL, config.SupplierConfig{
+		RecordsPath: "data",
+		Pagination:  &config.SupplierPaginationConfig{Type: config.PaginationCursor, CursorPath: "paging.next"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Suppliers[0].Pages), 2)
+	assert.Equal(t, len(repo.GetAllHotels()), 2)
+}
+
+func TestDirectDataLoaderService_WithLinkPagination(t *testing.T) {
+	pages := hotelPages([][]string{{"iJhz"}, {"f8c9"}, {"SjyX"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`</hotels?page=%d>; rel="next"`, page+1))
+		}
+		w.Write([]byte(pages(page)))
+	}))
+	defer mockHttpServer.Close()
+	report, repo, err := loadPaginatedSupplier(t, mockHttpServer.URL+"/hotels", config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationLink},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Suppliers[0].Pages), 3)
+	assert.Equal(t, report.Suppliers[0].Pages[2].URL, mockHttpServer.URL+"/hotels?page=3")
+	assert.Equal(t, len(repo.GetAllHotels()), 3)
+}
+
+func TestDirectDataLoaderService_RefusesNextLinkToAnotherOrigin(t *testing.T) {
+	redirected := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected++
+	}))
+	defer target.Close()
+	pages := hotelPages([][]string{{"iJhz"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/hotels?page=2>; rel="next"`, target.URL))
+		w.Write([]byte(pages(1)))
+	}))
+	defer mockHttpServer.Close()
+	report, _, err := loadPaginatedSupplier(t, mockHttpServer.URL+"/hotels", config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationLink},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, report.Suppliers[0].Error, "is not on the supplier origin")
+	assert.Equal(t, redirected, 0)
+}
+
+func TestDirectDataLoaderService_ReportsFailingPage(t *testing.T) {
+	pages := hotelPages([][]string{{"iJhz"}, {"f8c9"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(pages(1)))
+	}))
+	defer mockHttpServer.Close()
+	report, _, err := loadPaginatedSupplier(t, mockHttpServer.URL, config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationPage},
+	})
+	assert.Error(t, err)
+	supplierReport := report.Suppliers[0]
+	assert.Equal(t, supplierReport.Status, model.SupplierLoadStatusFailed)
+	assert.Equal(t, len(supplierReport.Pages), 2)
+	assert.Equal(t, supplierReport.Pages[0].Error, "")
+	assert.Equal(t, supplierReport.Pages[1].Page, 2)
+	assert.Contains(t, supplierReport.Pages[1].Error, "404")
+	assert.Contains(t, supplierReport.Error, "page 2")
+}
+
+func TestDirectDataLoaderService_StopsAtMaxPages(t *testing.T) {
+	requests := 0
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// a broken supplier always returns the same cursor
+		fmt.Fprintf(w, `{"hotels": %s, "next": "same"}`, hotelPages([][]string{{"iJhz"}})(1))
+	}))
+	defer mockHttpServer.Close()
+	report, _, err := loadPaginatedSupplier(t, mockHttpServer.URL, config.SupplierConfig{
+		RecordsPath: "hotels",
+		Pagination:  &config.SupplierPaginationConfig{Type: config.PaginationCursor, CursorPath: "next", MaxPages: 5},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, requests, 6)
+	assert.Contains(t, report.Suppliers[0].Error, "more than 5 pages")
+	assert.Equal(t, len(report.Suppliers[0].Pages), 6)
+	assert.Equal(t, report.Suppliers[0].Pages[5].Page, 6)
+	assert.Contains(t, report.Suppliers[0].Pages[5].Error, "more than 5 pages")
+}
+
+func TestDirectDataLoaderService_LoadsSupplierWithExactlyMaxPages(t *testing.T) {
+	pages := hotelPages([][]string{{"iJhz", "f8c9"}, {"SjyX"}})
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		w.Write([]byte(pages(page)))
+	}))
+	defer mockHttpServer.Close()
+	report, repo, err := loadPaginatedSupplier(t, mockHttpServer.URL, config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationPage, MaxPages: 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, len(repo.GetAllHotels()), 3)
+
+	report, _, err = loadPaginatedSupplier(t, mockHttpServer.URL, config.SupplierConfig{
+		Pagination: &config.SupplierPaginationConfig{Type: config.PaginationPage, MaxPages: 1},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, report.Suppliers[0].Error, "more than 1 pages")
+}
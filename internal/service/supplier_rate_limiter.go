@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SupplierRateLimit is the number of requests per second (Rate) a supplier
+// is sent, with up to Burst requests sent at once. A zero Rate is unlimited
+type SupplierRateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l SupplierRateLimit) getBurst() int {
+	if l.Burst < 1 {
+		return 1
+	}
+	return l.Burst
+}
+
+// rateLimiter is a token bucket shared by every request of a supplier, the
+// bucket holds up to burst tokens and is refilled at rate tokens per second
+// Requests reserve a token as they arrive, so concurrent requests are spread
+// in arrival order instead of racing for the next token
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  SupplierRateLimit
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(limit SupplierRateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		tokens: float64(limit.getBurst()),
+		now:    time.Now,
+	}
+}
+
+// wait blocks until the request can be sent or the context is cancelled
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil || l.limit.Rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.limit.Rate
+		if burst := float64(l.limit.getBurst()); l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.limit.Rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	err := sleepWithContext(ctx, delay)
+	if err != nil {
+		// give the reserved token back to the following requests
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+	}
+	return err
+}
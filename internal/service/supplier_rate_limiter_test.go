@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_SpreadsRequests(t *testing.T) {
+	limiter := newRateLimiter(SupplierRateLimit{Rate: 20, Burst: 2})
+	startedAt := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, limiter.wait(context.Background()))
+	}
+	// the burst goes straight through, the other two wait 50ms each
+	elapsed := time.Since(startedAt)
+	assert.GreaterOrEqual(t, elapsed, 90*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestRateLimiter_UnlimitedWithoutRate(t *testing.T) {
+	var limiter *rateLimiter
+	assert.Nil(t, limiter.wait(context.Background()))
+	limiter = newRateLimiter(SupplierRateLimit{})
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, limiter.wait(context.Background()))
+	}
+}
+
+func TestRateLimiter_ContextCancellationAbortsWait(t *testing.T) {
+	limiter := newRateLimiter(SupplierRateLimit{Rate: 0.1})
+	assert.Nil(t, limiter.wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.wait(ctx), context.DeadlineExceeded)
+}
+
+func TestSupplierHttpClient_RetriesAreRateLimited(t *testing.T) {
+	attempts := 0
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout, MaxRetries: 2})
+	client.limiter = newRateLimiter(SupplierRateLimit{Rate: 20})
+	startedAt := time.Now()
+	_, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	assert.Error(t, err)
+	assert.Equal(t, attempts, 3)
+	assert.GreaterOrEqual(t, time.Since(startedAt), 90*time.Millisecond)
+}
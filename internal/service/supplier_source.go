@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/utils"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// payloadResult is what the decoding of a payload tells about it, it drives
+// the pagination of the supplier
+type payloadResult struct {
+	records int
+	values  feedValues
+}
+
+// payloadHandler consumes the body of a single supplier payload as it was
+// served, location is the url or the file the payload was read from
+type payloadHandler func(location string, body io.Reader) (payloadResult, error)
+
+// readSupplierPayloads calls onPayload with every payload of the supplier
+// An http or https url is a single payload, a file:// url is either a single
+// file, a directory whose files are read in name order, or a glob pattern
+// whose matches are read in name order, every file being a supplier dump
+// The request of a single payload http or https url is made conditional when
+// validators are given, errNotModified is returned without calling onPayload
+// when the supplier answers 304, the validators of the payload are returned
+// otherwise. Paginated and file suppliers are always read in full
+func readSupplierPayloads(ctx context.Context, client *SupplierHttpClient, supplier config.SupplierConfig, validators cacheValidators, onPayload payloadHandler) (cacheValidators, error) {
+	path, isFile := config.FileSourcePath(supplier.URL)
+	if !isFile && supplier.Pagination != nil {
+		return cacheValidators{}, readSupplierPages(ctx, client, supplier, onPayload)
+	}
+	if !isFile {
+		resp, err := client.Get(ctx, supplier.URL, conditionalHeader(supplierHeaders(supplier), validators))
+		if err != nil {
+			return cacheValidators{}, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotModified {
+			return validators, errNotModified
+		}
+		_, err = onPayload(supplier.URL, resp.Body)
+		if err != nil {
+			return cacheValidators{}, err
+		}
+		return responseValidators(resp.Header), nil
+	}
+	if err := client.config.URLPolicy.CheckURL(supplier.URL); err != nil {
+		return cacheValidators{}, &model.URLPolicyError{Err: err}
+	}
+	files, err := resolveFileSource(path)
+	if err != nil {
+		// the path is already part of the file error
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return cacheValidators{}, &model.FileError{Path: path, Err: err}
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return cacheValidators{}, err
+		}
+		err = readFilePayload(file, onPayload)
+		if err != nil {
+			return cacheValidators{}, err
+		}
+	}
+	return cacheValidators{}, nil
+}
+
+func readFilePayload(file string, onPayload payloadHandler) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return &model.FileError{Path: file, Err: err}
+	}
+	defer f.Close()
+	_, err = onPayload(file, f)
+	if err != nil {
+		return &model.FileError{Path: file, Err: err}
+	}
+	return nil
+}
+
+// readSupplierPages walks every page of a paginated supplier, a page that
+// cannot be loaded stops the walk with a PageError and so does a supplier
+// having more pages than the configured maximum. The page following the last
+// allowed one is still fetched, page and offset pagination only stop on an
+// empty page so the supplier only fails if that page has records (or more
+// pages are announced)
+func readSupplierPages(ctx context.Context, client *SupplierHttpClient, supplier config.SupplierConfig, onPayload payloadHandler) error {
+	paginator := newSupplierPaginator(supplier.Pagination)
+	pageURL, err := paginator.first(supplier.URL)
+	if err != nil {
+		return &model.PageError{Page: 1, URL: utils.RedactURL(supplier.URL), Err: err}
+	}
+	maxPages := supplier.Pagination.GetMaxPages()
+	for page := 1; ; page++ {
+		var nextURL string
+		result, header, err := readPage(ctx, client, supplier, pageURL, onPayload)
+		if err == nil {
+			var more bool
+			nextURL, more, err = paginator.next(pageURL, result, header)
+			if err == nil && page > maxPages && (more || result.records > 0) {
+				err = fmt.Errorf("supplier has more than %d pages, raise max_pages", maxPages)
+			} else if err == nil && !more {
+				return nil
+			}
+		}
+		if err != nil {
+			return &model.PageError{Page: page, URL: utils.RedactURL(pageURL), Err: err}
+		}
+		pageURL = nextURL
+	}
+}
+
+func readPage(ctx context.Context, client *SupplierHttpClient, supplier config.SupplierConfig, pageURL string, onPayload payloadHandler) (payloadResult, http.Header, error) {
+	resp, err := client.Get(ctx, pageURL, supplierHeaders(supplier))
+	if err != nil {
+		return payloadResult{}, nil, err
+	}
+	defer resp.Body.Close()
+	result, err := onPayload(pageURL, resp.Body)
+	return result, resp.Header, err
+}
+
+// resolveFileSource returns the files of a file source in name order, hidden
+// files and sub directories of a directory source are skipped. A source that
+// resolves to no file is an error so an empty export does not wipe the supplier
+func resolveFileSource(path string) ([]string, error) {
+	var files []string
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err == nil && info.Mode().IsRegular() {
+				files = append(files, match)
+			}
+		}
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return []string{path}, nil
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no supplier dump found")
+	}
+	sort.Strings(files)
+	return files, nil
+}
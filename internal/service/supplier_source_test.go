@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSupplierDump(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestResolveFileSource(t *testing.T) {
+	dir := t.TempDir()
+	second := writeSupplierDump(t, dir, "dump-2.json", "[]")
+	first := writeSupplierDump(t, dir, "dump-1.json", "[]")
+	writeSupplierDump(t, dir, ".hidden.json", "[]")
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "archive"), 0o700))
+
+	files, err := resolveFileSource(first)
+	assert.Nil(t, err)
+	assert.Equal(t, files, []string{first})
+
+	files, err = resolveFileSource(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, files, []string{first, second})
+
+	files, err = resolveFileSource(filepath.Join(dir, "dump-?.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, files, []string{first, second})
+
+	_, err = resolveFileSource(filepath.Join(dir, "*.csv"))
+	assert.Error(t, err)
+	_, err = resolveFileSource(filepath.Join(dir, "archive"))
+	assert.Error(t, err)
+	_, err = resolveFileSource(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestDirectDataLoaderService_WithFileSources(t *testing.T) {
+	dir := t.TempDir()
+	writeSupplierDump(t, dir, "supplierA-1.json", supplierADataset)
+	writeSupplierDump(t, dir, "supplierA-2.json", strings.Replace(supplierADataset, ValidHotelId, "f8c9", 1))
+	supplierB := writeSupplierDump(t, dir, "supplierB.json", supplierBDataset)
+	suppliers := []config.SupplierConfig{
+		{Name: "supplierA", URL: "file://" + filepath.Join(dir, "supplierA-*.json")},
+		{Name: "supplierB", URL: "file://" + supplierB},
+	}
+	repo := repository.NewInMemoryHotelRepository()
+	loader := NewDirectDataLoaderService(suppliers, repo, logger)
+	report, err := loader.LoadData(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, report.Suppliers[0].RecordsReceived, 2)
+	assert.Equal(t, report.Suppliers[1].RecordsReceived, 1)
+	assert.Equal(t, len(repo.GetHotelsByHotelIds([]string{ValidHotelId, "f8c9"})), 2)
+}
+
+func TestDirectDataLoaderService_WithInvalidFileSource(t *testing.T) {
+	dir := t.TempDir()
+	invalid := writeSupplierDump(t, dir, "supplierA.json", "<html></html>")
+	for _, url := range []string{"file://" + invalid, "file://" + filepath.Join(dir, "missing")} {
+		repo := repository.NewInMemoryHotelRepository()
+		loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: url}}, repo, logger)
+		report, err := loader.LoadData(context.Background())
+		assert.Error(t, err)
+		var fileError *model.FileError
+		assert.ErrorAs(t, report.Suppliers[0].Err, &fileError)
+	}
+}
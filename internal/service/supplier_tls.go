@@ -0,0 +1,37 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"datamerge/internal/config"
+	"fmt"
+	"os"
+)
+
+// newSupplierTLSConfig builds the TLS configuration of a supplier, the files
+// are read on every call so renewed certificates are used by the next load
+func newSupplierTLSConfig(supplierTLS *config.SupplierTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: supplierTLS.GetMinVersion(),
+		ServerName: supplierTLS.ServerName,
+	}
+	if supplierTLS.CAFile != "" {
+		bundle, err := os.ReadFile(supplierTLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read tls ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("tls ca_file %s holds no PEM certificate", supplierTLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if supplierTLS.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(supplierTLS.CertFile, supplierTLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
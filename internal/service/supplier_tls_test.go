@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate is a certificate and its key signed by parent, or self
+// signed when parent is nil
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCertificate{cert: cert, key: key}
+}
+
+// writePem writes the certificate and its key to dir and returns their paths
+func (c *testCertificate) writePem(t *testing.T, dir, name string) (certFile, keyFile string) {
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	der, err := x509.MarshalECPrivateKey(c.key)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	return certFile, keyFile
+}
+
+// newMutualTLSServer starts a TLS server requiring a client certificate signed
+// by clientCA and writes the CA bundle of its own certificate to dir
+func newMutualTLSServer(t *testing.T, dir string, clientCA *testCertificate) (*httptest.Server, string) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(conditionalSupplierFeed))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	caFile := filepath.Join(dir, "supplier-ca.pem")
+	assert.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	return server, caFile
+}
+
+func loadTLSSupplier(t *testing.T, url string, supplierTLS *config.SupplierTLSConfig) *model.LoadReport {
+	supplier := config.SupplierConfig{Name: "supplierA", URL: url, TLS: supplierTLS}
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{supplier}, repository.NewInMemoryHotelRepository(), logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout})
+	report, _ := loader.LoadData(context.Background())
+	return report
+}
+
+func TestDirectDataLoaderService_WithMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCA := newTestCertificate(t, "partner client ca", nil)
+	server, caFile := newMutualTLSServer(t, dir, clientCA)
+	certFile, keyFile := newTestCertificate(t, "datamerge", clientCA).writePem(t, dir, "client")
+
+	report := loadTLSSupplier(t, server.URL, &config.SupplierTLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	assert.Equal(t, report.HotelsLoaded, 1)
+
+	// the httptest certificate is issued for example.com
+	report = loadTLSSupplier(t, server.URL, &config.SupplierTLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusSuccess)
+	report = loadTLSSupplier(t, server.URL, &config.SupplierTLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "supplier.com"})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+}
+
+func TestDirectDataLoaderService_MutualTLSRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	clientCA := newTestCertificate(t, "partner client ca", nil)
+	server, caFile := newMutualTLSServer(t, dir, clientCA)
+
+	report := loadTLSSupplier(t, server.URL, &config.SupplierTLSConfig{CAFile: caFile})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+
+	// a certificate from another authority is refused too
+	certFile, keyFile := newTestCertificate(t, "datamerge", newTestCertificate(t, "other ca", nil)).writePem(t, dir, "other")
+	report = loadTLSSupplier(t, server.URL, &config.SupplierTLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+}
+
+func TestDirectDataLoaderService_UntrustedSupplierCertificate(t *testing.T) {
+	dir := t.TempDir()
+	clientCA := newTestCertificate(t, "partner client ca", nil)
+	server, _ := newMutualTLSServer(t, dir, clientCA)
+	otherCAFile, _ := clientCA.writePem(t, dir, "client-ca")
+
+	report := loadTLSSupplier(t, server.URL, &config.SupplierTLSConfig{CAFile: otherCAFile})
+	assert.Equal(t, report.Suppliers[0].Status, model.SupplierLoadStatusFailed)
+	assert.Contains(t, report.Suppliers[0].Error, "certificate")
+}
+
+func TestNewSupplierTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCertificate(t, "datamerge", nil).writePem(t, dir, "client")
+	tlsConfig, err := newSupplierTLSConfig(&config.SupplierTLSConfig{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, ServerName: "supplier.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, tlsConfig.MinVersion, uint16(tls.VersionTLS12))
+	assert.Equal(t, tlsConfig.ServerName, "supplier.com")
+	assert.Equal(t, len(tlsConfig.Certificates), 1)
+	assert.NotNil(t, tlsConfig.RootCAs)
+
+	_, err = newSupplierTLSConfig(&config.SupplierTLSConfig{CAFile: keyFile})
+	assert.ErrorContains(t, err, "holds no PEM certificate")
+	_, err = newSupplierTLSConfig(&config.SupplierTLSConfig{CAFile: filepath.Join(dir, "missing.pem")})
+	assert.ErrorContains(t, err, "cannot read tls ca_file")
+	_, err = newSupplierTLSConfig(&config.SupplierTLSConfig{CertFile: certFile, KeyFile: certFile})
+	assert.ErrorContains(t, err, "cannot load tls client certificate")
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const maxSupplierRedirects = 10
+
+// blockedPrefixes are the networks a supplier cannot resolve to besides the
+// loopback, private, link-local and unspecified addresses
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+func isBlockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockPrivateNetworks is the Control function of the dialer of the supplier
+// connections, it runs once the host is resolved so a hostname resolving to a
+// private address (or rebinding to one) is refused as well as an IP address
+func blockPrivateNetworks(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if isBlockedAddress(addr) {
+		return &model.URLPolicyError{Err: fmt.Errorf("address %s is in a private network", addr)}
+	}
+	return nil
+}
+
+var (
+	privateNetworkGuardOnce      sync.Once
+	privateNetworkGuardTransport *http.Transport
+)
+
+// privateNetworkGuard returns the transport refusing connections to private
+// networks, it is shared by every supplier client so connections are reused
+func privateNetworkGuard() *http.Transport {
+	privateNetworkGuardOnce.Do(func() {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   blockPrivateNetworks,
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		privateNetworkGuardTransport = transport
+	})
+	return privateNetworkGuardTransport
+}
+
+// checkRedirect applies the url policy to every redirect of a supplier request
+// and refuses redirects from https to http. The headers set by the supplier
+// auth are dropped from a redirect to another host or port, net/http only
+// drops the Authorization and Cookie headers
+func checkRedirect(policy config.SupplierURLPolicy, auth *config.SupplierAuthConfig) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxSupplierRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxSupplierRedirects)
+		}
+		if via[len(via)-1].URL.Scheme == "https" && req.URL.Scheme != "https" {
+			return &model.URLPolicyError{Err: errors.New("redirect from https to http")}
+		}
+		if err := policy.CheckURL(req.URL.String()); err != nil {
+			return &model.URLPolicyError{Err: err}
+		}
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for _, header := range authHeaders(auth) {
+				req.Header.Del(header)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"datamerge/internal/config"
+	"datamerge/internal/model"
+	"datamerge/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBlockedAddress(t *testing.T) {
+	for _, blocked := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "100.64.0.1", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "::"} {
+		assert.True(t, isBlockedAddress(netip.MustParseAddr(blocked)), blocked)
+	}
+	for _, allowed := range []string{"8.8.8.8", "203.0.113.10", "2606:4700::1111"} {
+		assert.False(t, isBlockedAddress(netip.MustParseAddr(allowed)), allowed)
+	}
+}
+
+func TestSupplierHttpClient_BlocksPrivateNetworks(t *testing.T) {
+	requests := 0
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{
+		Timeout:        DefaultSupplierTimeout,
+		MaxRetries:     3,
+		InitialBackoff: time.Hour,
+		URLPolicy:      config.SupplierURLPolicy{BlockPrivateNetworks: true},
+	})
+	// a url policy error is not retried
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Get(ctx, mockHttpServer.URL, nil)
+	var policyErr *model.URLPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.Nil(t, ctx.Err())
+	assert.Equal(t, requests, 0)
+}
+
+func TestSupplierHttpClient_ChecksRedirects(t *testing.T) {
+	redirected := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected++
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+targetURL.Port()+"/hotels?token=secret", http.StatusFound)
+	}))
+	defer mockHttpServer.Close()
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{
+		Timeout:   DefaultSupplierTimeout,
+		URLPolicy: config.SupplierURLPolicy{AllowedHosts: []string{"127.0.0.1"}},
+	})
+	_, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	var policyErr *model.URLPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.Contains(t, err.Error(), `host "localhost" is not allowed`)
+	assert.False(t, strings.Contains(err.Error(), "secret"))
+	assert.Equal(t, redirected, 0)
+}
+
+func TestCheckRedirect_RefusesHttpsDowngrade(t *testing.T) {
+	via := []*http.Request{httptest.NewRequest(http.MethodGet, "https://api.supplier.com/hotels", nil)}
+	err := checkRedirect(config.SupplierURLPolicy{}, nil)(httptest.NewRequest(http.MethodGet, "http://api.supplier.com/hotels", nil), via)
+	var policyErr *model.URLPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.Nil(t, checkRedirect(config.SupplierURLPolicy{}, nil)(httptest.NewRequest(http.MethodGet, "https://cdn.supplier.com/hotels", nil), via))
+}
+
+func TestSupplierHttpClient_DropsAuthHeadersOnCrossHostRedirect(t *testing.T) {
+	t.Setenv("SUPPLIER_API_KEY", "s3cret")
+	t.Setenv("SUPPLIER_HMAC_SECRET", "hmac-secret")
+	var received http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	mockHttpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hotels":
+			received = r.Header.Clone()
+		case "/same-host":
+			http.Redirect(w, r, "/hotels", http.StatusFound)
+		default:
+			http.Redirect(w, r, "http://localhost:"+targetURL.Port()+"/hotels", http.StatusFound)
+		}
+	}))
+	defer mockHttpServer.Close()
+	auth := &config.SupplierAuthConfig{
+		Type:    config.SupplierAuthHmac,
+		Secret:  config.SecretRef{Env: "SUPPLIER_HMAC_SECRET"},
+		Headers: map[string]config.SecretRef{"X-Api-Key": {Env: "SUPPLIER_API_KEY"}},
+	}
+	client := NewSupplierHttpClient(SupplierHttpClientConfig{Timeout: DefaultSupplierTimeout, Auth: auth})
+	resp, err := client.Get(context.Background(), mockHttpServer.URL, nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	for _, header := range authHeaders(auth) {
+		assert.Empty(t, received.Get(header), header)
+	}
+
+	// the redirected request keeps its headers when the host does not change
+	resp, err = client.Get(context.Background(), mockHttpServer.URL+"/same-host", nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, received.Get("X-Api-Key"), "s3cret")
+}
+
+func TestDirectDataLoaderService_RefusesFileSourceOutsideURLPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSupplierDump(t, dir, "supplierA.json", conditionalSupplierFeed)
+	loader := NewDirectDataLoaderService([]config.SupplierConfig{{Name: "supplierA", URL: "file://" + path}}, repository.NewInMemoryHotelRepository(), logger)
+	loader.SetHttpClientConfig(SupplierHttpClientConfig{
+		Timeout:   DefaultSupplierTimeout,
+		URLPolicy: config.SupplierURLPolicy{AllowedSchemes: []string{"https"}},
+	})
+	report, err := loader.LoadData(context.Background())
+	assert.Error(t, err)
+	var policyErr *model.URLPolicyError
+	assert.ErrorAs(t, report.Suppliers[0].Err, &policyErr)
+}
@@ -9,17 +9,25 @@ import (
 func NewLogger(logLevel string) *logrus.Logger {
 	logger := logrus.New()
 	logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrusLevel := logrus.WarnLevel
 	logger.Out = os.Stdout
+	logger.SetLevel(ParseLogLevel(logLevel))
+	return logger
+}
+
+// ParseLogLevel maps the LOG_LEVEL setting to a logrus level, unknown
+// levels fall back to warn
+func ParseLogLevel(logLevel string) logrus.Level {
+	logrusLevel := logrus.WarnLevel
 	logLevel = strings.ToLower(logLevel)
 	switch logLevel {
 	case "debug":
 		logrusLevel = logrus.DebugLevel
+	case "info":
+		logrusLevel = logrus.InfoLevel
 	case "warn":
 		logrusLevel = logrus.WarnLevel
 	case "error":
 		logrusLevel = logrus.ErrorLevel
 	}
-	logger.SetLevel(logrusLevel)
-	return logger
+	return logrusLevel
 }
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveQueryParams are the url query parameters commonly carrying
+// credentials, matched case-insensitively on a substring
+var sensitiveQueryParams = []string{"key", "token", "secret", "password", "signature", "sig", "auth", "credential"}
+
+// RedactURL masks the password of the url user info and the values of the
+// query parameters that look like credentials, so the url can be logged or
+// reported. Extra secret values (e.g. resolved api keys) are masked wherever
+// they appear. Urls that cannot be parsed are fully redacted
+func RedactURL(rawURL string, secrets ...string) string {
+	// file urls hold paths and glob patterns, not credentials
+	if strings.HasPrefix(strings.ToLower(rawURL), "file:") {
+		return rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	if parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+		}
+	}
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for name := range query {
+			if isSensitiveQueryParam(name) {
+				query[name] = []string{redacted}
+			}
+		}
+		parsed.RawQuery = query.Encode()
+	}
+	result := parsed.String()
+	for _, secret := range secrets {
+		if secret != "" {
+			result = strings.ReplaceAll(result, secret, redacted)
+			result = strings.ReplaceAll(result, url.QueryEscape(secret), redacted)
+		}
+	}
+	return result
+}
+
+func isSensitiveQueryParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveQueryParams {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
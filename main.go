@@ -1,31 +1,201 @@
 package main
 
 import (
+	"context"
 	config "datamerge/internal/config"
 	handlers "datamerge/internal/handler"
+	"datamerge/internal/model"
 	"datamerge/internal/repository"
 	service "datamerge/internal/service"
 	"datamerge/internal/utils"
+	"errors"
+	"flag"
+	"github.com/sirupsen/logrus"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	config, err := config.GetConfigFromEnv()
+	env := flag.String("env", os.Getenv(config.AppEnvVariable), "environment whose app.<env>.env config file is used, defaults to APP_ENV or local")
+	flag.Parse()
+
+	appConfig, err := config.LoadConfig(*env, config.DefaultPath)
 	if err != nil {
-		panic("unable to read config, please verify app.<env>.env exists")
+		log.Fatalf("unable to read config, please verify app.<env>.env exists and is valid: %s", err)
 	}
-	logger := utils.NewLogger(config.GetLogLevel())
+	logger := utils.NewLogger(appConfig.GetLogLevel())
+
+	// cancelled on SIGINT/SIGTERM, aborting in-flight supplier fetches
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	repo := repository.NewInMemoryHotelRepository()
 
-	dataLoaderService := service.NewDirectDataLoaderService(config.GetSupplierConfig(), repo, logger)
-	dataLoaderService.LoadData()
+	if err := model.DefaultHotelLoaderDataRegistry.RegisterMappings(appConfig.GetMappings()); err != nil {
+		log.Fatalf("unable to register supplier mappings: %s", err)
+	}
+	dataLoaderService := service.NewDirectDataLoaderService(appConfig.GetSuppliers(), repo, logger)
+	if err := dataLoaderService.ValidateSuppliers(appConfig.GetSuppliers()); err != nil {
+		log.Fatalf("unable to load suppliers: %s", err)
+	}
+	dataLoaderService.SetHttpClientConfig(supplierHttpClientConfig(appConfig))
+	dataLoaderService.SetConcurrency(appConfig.GetSupplierConcurrency())
+	dataLoaderService.SetBreakerConfig(supplierBreakerConfig(appConfig))
+	dataLoaderService.SetRateLimit(supplierRateLimit(appConfig))
+	dataLoaderService.SetPayloadLimits(supplierPayloadLimits(appConfig))
+	dataLoaderService.SetDeadLetterCapacity(appConfig.GetDeadLetterCapacity())
+	dataLoaderService.SetPayloadArchive(payloadArchiveConfig(appConfig))
+	scheduler := service.NewDataLoaderScheduler(dataLoaderService, appConfig.GetLoadInterval(), appConfig.GetLoadJitter(), logger)
+
+	// the last snapshotted catalog is served while the suppliers are loaded,
+	// without one the first load completes before the server starts
+	snapshotsDone := make(chan struct{})
+	if appConfig.GetSnapshotDir() == "" {
+		close(snapshotsDone)
+		scheduler.RunOnce(ctx)
+	} else {
+		snapshotStore := repository.NewFileCatalogSnapshotStore(appConfig.GetSnapshotDir(), appConfig.GetSnapshotKeep())
+		snapshots := service.NewCatalogSnapshotService(snapshotStore, repo, dataLoaderService, appConfig.GetSnapshotInterval(), logger)
+		if snapshots.Restore() != nil {
+			dataLoaderService.MarkCatalogRestored()
+			go scheduler.RunOnce(ctx)
+		} else {
+			scheduler.RunOnce(ctx)
+		}
+		go func() {
+			defer close(snapshotsDone)
+			snapshots.Start(ctx)
+		}()
+	}
+	go scheduler.Start(ctx)
+
+	configWatcher := config.NewConfigWatcher(appConfig, config.DefaultPath)
+	configWatcher.AddValidator(func(current config.ImmutableConfig) error {
+		return validateAdapters(model.DefaultHotelLoaderDataRegistry, current)
+	})
+	configWatcher.OnError(func(err error) {
+		logger.Warn("config change rejected, keeping previous config: ", err)
+	})
+	configWatcher.OnChange(func(previous, current config.ImmutableConfig) {
+		applyConfigChange(ctx, logger, dataLoaderService, previous, current)
+	})
+	go func() {
+		if err := configWatcher.Start(ctx); err != nil {
+			logger.Warn("unable to watch config files, config changes require a restart: ", err)
+		}
+	}()
 
 	svc := service.NewHotelService(repo)
 	hotelHandler := handlers.NewHotelHandler(svc)
+	loaderHandler := handlers.NewLoaderHandler(scheduler, dataLoaderService, dataLoaderService, dataLoaderService, model.DefaultHotelLoaderDataRegistry)
 
 	hotelHandler.SetupHandlers()
+	adminMux := http.NewServeMux()
+	loaderHandler.SetupHandlers(adminMux)
+
+	server := &http.Server{Addr: ":8080"}
+	adminServer := &http.Server{Addr: appConfig.GetAdminAddr(), Handler: adminMux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		adminServer.Shutdown(shutdownCtx)
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	// wait for the snapshot taken on shutdown
+	<-snapshotsDone
+}
+
+func supplierHttpClientConfig(appConfig config.ImmutableConfig) service.SupplierHttpClientConfig {
+	return service.SupplierHttpClientConfig{
+		Timeout:        appConfig.GetSupplierTimeout(),
+		MaxRetries:     appConfig.GetSupplierMaxRetries(),
+		InitialBackoff: appConfig.GetSupplierRetryBackoff(),
+		MaxBackoff:     appConfig.GetSupplierRetryMaxBackoff(),
+		URLPolicy:      appConfig.GetSupplierURLPolicy(),
+	}
+}
+
+func supplierBreakerConfig(appConfig config.ImmutableConfig) service.SupplierBreakerConfig {
+	return service.SupplierBreakerConfig{
+		Threshold: appConfig.GetSupplierBreakerThreshold(),
+		Cooldown:  appConfig.GetSupplierBreakerCooldown(),
+	}
+}
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+func supplierRateLimit(appConfig config.ImmutableConfig) service.SupplierRateLimit {
+	return service.SupplierRateLimit{
+		Rate:  appConfig.GetSupplierRateLimit(),
+		Burst: appConfig.GetSupplierRateBurst(),
+	}
+}
+
+func supplierPayloadLimits(appConfig config.ImmutableConfig) service.SupplierPayloadLimits {
+	return service.SupplierPayloadLimits{
+		MaxPayloadBytes: appConfig.GetSupplierMaxPayloadBytes(),
+		MaxRecords:      appConfig.GetSupplierMaxRecords(),
+		MaxRecordBytes:  appConfig.GetSupplierMaxRecordBytes(),
+	}
+}
+
+func payloadArchiveConfig(appConfig config.ImmutableConfig) service.PayloadArchiveConfig {
+	return service.PayloadArchiveConfig{
+		Dir:       appConfig.GetArchiveDir(),
+		Retention: appConfig.GetArchiveRetention(),
+		MaxRuns:   appConfig.GetArchiveMaxRuns(),
+	}
+}
+
+// validateAdapters checks that the mappings of a reloaded config can be
+// registered and that the adapter of every supplier is registered once they
+// are, without changing the registry
+func validateAdapters(adapters *model.HotelLoaderDataRegistry, current config.ImmutableConfig) error {
+	adapters = adapters.Clone()
+	if err := adapters.RegisterMappings(current.GetMappings()); err != nil {
+		return err
+	}
+	return service.ValidateSupplierAdapters(adapters, current.GetSuppliers())
+}
+
+// applyConfigChange applies a reloaded config to the running services, the
+// log level is changed straight away, new supplier mappings are registered
+// and only the suppliers whose config changed are fetched again
+func applyConfigChange(ctx context.Context, logger *logrus.Logger, dataLoaderService *service.DirectDataLoaderService, previous, current config.ImmutableConfig) {
+	logger.SetLevel(utils.ParseLogLevel(current.GetLogLevel()))
+	dataLoaderService.SetHttpClientConfig(supplierHttpClientConfig(current))
+	dataLoaderService.SetConcurrency(current.GetSupplierConcurrency())
+	dataLoaderService.SetBreakerConfig(supplierBreakerConfig(current))
+	dataLoaderService.SetRateLimit(supplierRateLimit(current))
+	dataLoaderService.SetPayloadLimits(supplierPayloadLimits(current))
+	dataLoaderService.SetDeadLetterCapacity(current.GetDeadLetterCapacity())
+	dataLoaderService.SetPayloadArchive(payloadArchiveConfig(current))
+	if err := model.DefaultHotelLoaderDataRegistry.RegisterMappings(current.GetMappings()); err != nil {
+		logger.Warn("unable to register supplier mappings: ", err)
+	}
+
+	changedSuppliers := config.ChangedConfigSuppliers(previous, current)
+	logger.WithField("changed_suppliers", changedSuppliers).Info("config reloaded")
+	if len(changedSuppliers) == 0 {
+		return
+	}
+	if err := dataLoaderService.ValidateSuppliers(current.GetSuppliers()); err != nil {
+		logger.Warn("supplier config change rejected, keeping previous suppliers: ", err)
+		return
+	}
+	dataLoaderService.SetSuppliers(current.GetSuppliers())
+	go dataLoaderService.ReloadSuppliers(ctx, changedSuppliers)
 }